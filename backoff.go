@@ -0,0 +1,105 @@
+package tart
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// Backoff computes increasing delays between retries of an operation.
+// Each zero field takes its value from DefaultBackoff, so the zero value
+// behaves like DefaultBackoff.
+type Backoff struct {
+	// Initial is the first delay.
+	Initial time.Duration `json:"initial"`
+	// Max caps every delay, including jitter. A negative Max means no cap.
+	Max time.Duration `json:"max"`
+	// Multiplier scales the delay after each retry. Values below 1 are
+	// treated as 1, giving a constant delay.
+	Multiplier float64 `json:"multiplier"`
+	// Jitter randomizes each delay by up to this fraction in either
+	// direction, e.g. 0.2 yields delays within ±20%. A negative Jitter
+	// disables it.
+	Jitter float64 `json:"jitter"`
+
+	next  time.Duration
+	clock clock
+}
+
+// DefaultBackoff returns the backoff used for polling when none is configured.
+func DefaultBackoff() Backoff {
+	return Backoff{
+		Initial:    500 * time.Millisecond,
+		Max:        5 * time.Second,
+		Multiplier: 2,
+		Jitter:     0.2,
+	}
+}
+
+// Next returns the delay before the next retry and advances the backoff.
+func (b *Backoff) Next() time.Duration {
+	defaults := DefaultBackoff()
+	initial, max, multiplier, jitter := b.Initial, b.Max, b.Multiplier, b.Jitter
+	if initial <= 0 {
+		initial = defaults.Initial
+	}
+	if max == 0 {
+		max = defaults.Max
+	}
+	if multiplier == 0 {
+		multiplier = defaults.Multiplier
+	}
+	if jitter == 0 {
+		jitter = defaults.Jitter
+	}
+	if b.next <= 0 {
+		b.next = initial
+	}
+	d := b.next
+
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	b.next = time.Duration(float64(b.next) * multiplier)
+	if max > 0 && b.next > max {
+		b.next = max
+	}
+
+	if jitter > 0 {
+		d += time.Duration((rand.Float64()*2 - 1) * jitter * float64(d))
+	}
+	if max > 0 && d > max {
+		d = max
+	}
+	if d < 0 {
+		d = 0
+	}
+	return d
+}
+
+// Reset restarts the backoff from its initial delay.
+func (b *Backoff) Reset() {
+	b.next = 0
+}
+
+// Wait sleeps for the next delay, returning early with the context's error
+// if it is done first.
+func (b *Backoff) Wait(ctx context.Context) error {
+	d := b.Next()
+	if b.clock == nil {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			return nil
+		}
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-b.clock.After(d):
+		return nil
+	}
+}
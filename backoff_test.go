@@ -0,0 +1,114 @@
+package tart
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBackoffNext(t *testing.T) {
+	tests := []struct {
+		name    string
+		backoff Backoff
+		want    []time.Duration
+	}{
+		{
+			name:    "multiplier grows the delay up to max",
+			backoff: Backoff{Initial: time.Second, Max: 5 * time.Second, Multiplier: 2, Jitter: -1},
+			want:    []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second},
+		},
+		{
+			name:    "multiplier below one gives a constant delay",
+			backoff: Backoff{Initial: time.Second, Multiplier: 0.5, Jitter: -1},
+			want:    []time.Duration{time.Second, time.Second, time.Second},
+		},
+		{
+			name:    "negative max means no cap",
+			backoff: Backoff{Initial: 4 * time.Second, Max: -1, Multiplier: 2, Jitter: -1},
+			want:    []time.Duration{4 * time.Second, 8 * time.Second, 16 * time.Second},
+		},
+		{
+			name:    "zero initial takes the default",
+			backoff: Backoff{Max: 5 * time.Second, Jitter: -1},
+			want:    []time.Duration{500 * time.Millisecond, time.Second, 2 * time.Second},
+		},
+		{
+			name:    "zero multiplier takes the default",
+			backoff: Backoff{Initial: time.Second, Jitter: -1},
+			want:    []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := tt.backoff
+			for i, want := range tt.want {
+				if got := b.Next(); got != want {
+					t.Errorf("delay %d = %v, want %v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	b := Backoff{Initial: time.Second, Max: -1, Multiplier: 1, Jitter: 0.2}
+	for i := 0; i < 100; i++ {
+		if d := b.Next(); d < 800*time.Millisecond || d > 1200*time.Millisecond {
+			t.Fatalf("delay %v outside ±20%% of 1s", d)
+		}
+	}
+}
+
+func TestBackoffJitterRespectsMax(t *testing.T) {
+	b := Backoff{Initial: time.Second, Max: time.Second, Multiplier: 1, Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		if d := b.Next(); d > time.Second {
+			t.Fatalf("delay %v exceeds max", d)
+		}
+	}
+}
+
+func TestBackoffZeroValueKeepsSettings(t *testing.T) {
+	var b Backoff
+	b.Next()
+	if b != (Backoff{next: b.next}) {
+		t.Errorf("Next modified the configured fields: %+v", b)
+	}
+}
+
+func TestBackoffReset(t *testing.T) {
+	b := Backoff{Initial: time.Second, Multiplier: 2, Jitter: -1}
+	b.Next()
+	b.Next()
+	b.Reset()
+	if got := b.Next(); got != time.Second {
+		t.Errorf("delay after Reset = %v, want 1s", got)
+	}
+}
+
+func TestBackoffWaitZeroValueWithClock(t *testing.T) {
+	clk := newFakeClock()
+	tart := &Tart{clk: clk}
+	b := tart.pollBackoff()
+	for i := 0; i < 3; i++ {
+		if err := b.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait: %v", err)
+		}
+	}
+	waits := clk.recordedWaits()
+	if len(waits) != 3 {
+		t.Fatalf("clock waited %d times, want 3", len(waits))
+	}
+	if waits[0] < 400*time.Millisecond || waits[0] > 600*time.Millisecond {
+		t.Errorf("first wait %v, want about 500ms", waits[0])
+	}
+}
+
+func TestBackoffWaitContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	b := Backoff{Initial: time.Hour}
+	if err := b.Wait(ctx); err != context.Canceled {
+		t.Errorf("Wait = %v, want context.Canceled", err)
+	}
+}
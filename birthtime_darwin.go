@@ -0,0 +1,18 @@
+//go:build darwin
+
+package tart
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// birthTime returns a file's creation time, falling back to its modification
+// time if the file system doesn't record one.
+func birthTime(info os.FileInfo) time.Time {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok && st.Birthtimespec.Sec > 0 {
+		return time.Unix(st.Birthtimespec.Unix())
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,14 @@
+//go:build !darwin
+
+package tart
+
+import (
+	"os"
+	"time"
+)
+
+// birthTime returns a file's modification time; creation times aren't
+// available on this platform.
+func birthTime(info os.FileInfo) time.Time {
+	return info.ModTime()
+}
@@ -0,0 +1,34 @@
+package tart
+
+import "time"
+
+// clock abstracts the passage of time so that polling, backoff and timeouts
+// can be driven deterministically.
+type clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+	Sleep(d time.Duration)
+}
+
+// realClock is the clock backed by the time package.
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+
+// clock returns the instance's clock, defaulting to the real one.
+func (t *Tart) clock() clock {
+	if t.clk != nil {
+		return t.clk
+	}
+	return realClock{}
+}
+
+// pollBackoff returns a fresh copy of the instance's PollBackoff that waits
+// on the instance's clock.
+func (t *Tart) pollBackoff() Backoff {
+	b := t.PollBackoff
+	b.clock = t.clock()
+	return b
+}
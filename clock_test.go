@@ -0,0 +1,55 @@
+package tart
+
+import (
+	"sync"
+	"time"
+)
+
+// fakeClock is a clock whose time only moves when it's waited on: After
+// fires immediately and Sleep returns immediately, both advancing Now by the
+// requested duration and recording it.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	waits  []time.Duration
+	onWait func(d time.Duration)
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.waits = append(c.waits, d)
+	onWait := c.onWait
+	c.mu.Unlock()
+	if onWait != nil {
+		onWait(d)
+	}
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.advance(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.Now()
+	return ch
+}
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.advance(d)
+}
+
+// recordedWaits returns the durations waited so far.
+func (c *fakeClock) recordedWaits() []time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]time.Duration(nil), c.waits...)
+}
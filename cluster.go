@@ -0,0 +1,115 @@
+package tart
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Cluster represents a group of VMs run together whose serial output is
+// multiplexed into a single stream.
+type Cluster struct {
+	Handles []*RunHandle `json:"handles"`
+
+	done chan struct{}
+	err  error
+}
+
+// prefixWriter writes each line to a shared writer prefixed with a label.
+type prefixWriter struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix []byte
+}
+
+func (p *prefixWriter) Write(line []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, err := p.w.Write(append(append([]byte{}, p.prefix...), line...)); err != nil {
+		return 0, err
+	}
+	return len(line), nil
+}
+
+// RunCluster runs the named VMs in the background with the same options and
+// writes their serial output to w, prefixing each line with "[name] ".
+// Once any VM exits, the others are stopped; use Wait to observe this.
+// It returns once every VM is up, or an error if no names are given or any VM
+// fails to start, in which case the VMs already started are stopped.
+func (t *Tart) RunCluster(names []string, options RunOptions, w io.Writer) (*Cluster, error) {
+	if len(names) == 0 {
+		return nil, errors.New("cluster must have at least one VM")
+	}
+	var mu sync.Mutex
+	handles := make([]*RunHandle, len(names))
+	errs := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			opts := options
+			opts.SerialOutput = &prefixWriter{mu: &mu, w: w, prefix: []byte("[" + name + "] ")}
+			handles[i], errs[i] = t.RunDetached(name, opts)
+			if errs[i] != nil {
+				errs[i] = fmt.Errorf("failed to run VM %s: %w", name, errs[i])
+			}
+		}(i, name)
+	}
+	wg.Wait()
+
+	c := &Cluster{done: make(chan struct{})}
+	for _, h := range handles {
+		if h != nil {
+			c.Handles = append(c.Handles, h)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		c.Stop()
+		return nil, err
+	}
+
+	exited := make(chan *RunHandle, len(c.Handles))
+	for _, h := range c.Handles {
+		go func(h *RunHandle) {
+			<-h.Done()
+			exited <- h
+		}(h)
+	}
+	go func() {
+		first := <-exited
+		c.err = first.Wait()
+		c.Stop()
+		close(c.done)
+	}()
+	return c, nil
+}
+
+// Done returns a channel that is closed once every VM in the cluster has exited.
+func (c *Cluster) Done() <-chan struct{} {
+	return c.done
+}
+
+// Wait waits for the cluster to shut down, which happens once any VM exits.
+// It returns the error of the first VM to exit, if any.
+func (c *Cluster) Wait() error {
+	<-c.done
+	return c.err
+}
+
+// Stop stops every VM in the cluster.
+// It returns an error listing the VMs that couldn't be stopped.
+func (c *Cluster) Stop() error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(c.Handles))
+	for i, h := range c.Handles {
+		wg.Add(1)
+		go func(i int, h *RunHandle) {
+			defer wg.Done()
+			errs[i] = h.Stop()
+		}(i, h)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
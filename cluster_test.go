@@ -0,0 +1,45 @@
+package tart
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRunClusterRejectsNoNames(t *testing.T) {
+	tests := []struct {
+		name  string
+		names []string
+	}{
+		{"nil", nil},
+		{"empty", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeTart(t, runningScript)
+			var out syncBuffer
+			c, err := f.RunCluster(tt.names, RunOptions{}, &out)
+			if err == nil || c != nil {
+				t.Fatalf("RunCluster(%v) = %v, %v; want an error", tt.names, c, err)
+			}
+		})
+	}
+}
+
+func TestRunClusterPrefixesOutput(t *testing.T) {
+	f := newFakeTart(t, runningScript)
+	f.setList(t, `[{"name":"a","state":"stopped"},{"name":"b","state":"stopped"}]`)
+	var out syncBuffer
+	c, err := f.RunCluster([]string{"a", "b"}, RunOptions{}, &out)
+	if err != nil {
+		t.Fatalf("RunCluster: %v", err)
+	}
+	if err := c.Stop(); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	<-c.Done()
+	for _, want := range []string{"[a] VM is up", "[b] VM is up"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output %q doesn't contain %q", out.String(), want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package tart
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// macOSSharedDir is where macOS guests automount directories shared with --dir.
+const macOSSharedDir = "/Volumes/My Shared Files"
+
+// CopyToGuest copies a local file into a running VM through a directory it
+// shares with the host, and returns the path at which the guest sees it.
+// The VM must have been started by this instance with RunDetached or
+// RunUntilLog and at least one DirMount; shares can't be added to a running
+// VM. The file is written below the first mount at guestRelPath. macOS guests
+// get an absolute path under "/Volumes/My Shared Files"; other guests get a
+// path relative to wherever they mount the shared directories.
+// It returns an error if the VM has no shared directory or the copy fails.
+func (t *Tart) CopyToGuest(name string, localPath string, guestRelPath string) (string, error) {
+	h, ok := t.handle(name)
+	if !ok {
+		return "", fmt.Errorf("VM %s is not running under this instance", name)
+	}
+	if len(h.options.Dir) == 0 {
+		return "", fmt.Errorf("VM %s has no shared directory; run it with a DirMount to copy files into it", name)
+	}
+	rel := path.Clean("/" + filepath.ToSlash(guestRelPath))[1:]
+	if rel == "" {
+		return "", errors.New("guest path must name a file")
+	}
+	mount := h.options.Dir[0]
+	mountName := mount.Name
+	if mountName == "" {
+		mountName = filepath.Base(mount.Path)
+	}
+
+	dst := filepath.Join(t.resolvePath(mount.Path), filepath.FromSlash(rel))
+	if err := t.copyFile(t.resolvePath(localPath), dst); err != nil {
+		return "", err
+	}
+
+	guestPath := path.Join(mountName, rel)
+	if config, err := t.Config(name); err == nil && config.OS == "darwin" {
+		guestPath = path.Join(macOSSharedDir, guestPath)
+	}
+	return guestPath, nil
+}
+
+// copyFile copies the file at src to dst, creating dst's parent directories.
+func (t *Tart) copyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", src, err)
+	}
+	defer in.Close()
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(dst), err)
+	}
+	out, err := t.createFile(dst, false)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", dst, err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to copy to %s: %w", dst, err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to copy to %s: %w", dst, err)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package tart
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCopyToGuest(t *testing.T) {
+	tests := []struct {
+		name     string
+		os       string
+		mount    DirMount
+		guestRel string
+		want     string
+		wantFile string
+	}{
+		{
+			name:     "macOS guest",
+			os:       "darwin",
+			mount:    DirMount{Name: "src"},
+			guestRel: "sub/file.txt",
+			want:     "/Volumes/My Shared Files/src/sub/file.txt",
+			wantFile: "sub/file.txt",
+		},
+		{
+			name:     "linux guest",
+			os:       "linux",
+			mount:    DirMount{Name: "src"},
+			guestRel: "file.txt",
+			want:     "src/file.txt",
+			wantFile: "file.txt",
+		},
+		{
+			name:     "anonymous mount",
+			os:       "linux",
+			mount:    DirMount{},
+			guestRel: "file.txt",
+			want:     "share/file.txt",
+			wantFile: "file.txt",
+		},
+		{
+			name:     "path escaping the mount",
+			os:       "linux",
+			mount:    DirMount{Name: "src"},
+			guestRel: "../../etc/file.txt",
+			want:     "src/etc/file.txt",
+			wantFile: "etc/file.txt",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeTart(t, `
+case "$2" in
+--help) echo "--dir"; exit 0;;
+esac`+runningScript)
+			f.setList(t, `[{"name":"vm","state":"stopped"}]`)
+			f.addVM(t, "vm", `{"os":"`+tt.os+`"}`)
+			local := filepath.Join(t.TempDir(), "local.txt")
+			if err := os.WriteFile(local, []byte("payload"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			mount := tt.mount
+			mount.Path = filepath.Join(t.TempDir(), "share")
+			h, err := f.RunDetached("vm", RunOptions{Dir: []DirMount{mount}})
+			if err != nil {
+				t.Fatalf("RunDetached: %v", err)
+			}
+			defer h.Stop()
+			got, err := f.CopyToGuest("vm", local, tt.guestRel)
+			if err != nil {
+				t.Fatalf("CopyToGuest: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("CopyToGuest = %q, want %q", got, tt.want)
+			}
+			data, err := os.ReadFile(filepath.Join(mount.Path, filepath.FromSlash(tt.wantFile)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != "payload" {
+				t.Errorf("copied file = %q, want payload", data)
+			}
+		})
+	}
+}
+
+func TestCopyToGuestNeedsShare(t *testing.T) {
+	f := newFakeTart(t, runningScript)
+	f.setList(t, `[{"name":"vm","state":"stopped"}]`)
+	if _, err := f.CopyToGuest("vm", "local.txt", "file.txt"); err == nil {
+		t.Error("CopyToGuest to a VM not running under the instance succeeded")
+	}
+	h, err := f.RunDetached("vm", RunOptions{})
+	if err != nil {
+		t.Fatalf("RunDetached: %v", err)
+	}
+	defer h.Stop()
+	if _, err := f.CopyToGuest("vm", "local.txt", "file.txt"); err == nil {
+		t.Error("CopyToGuest to a VM without a DirMount succeeded")
+	}
+}
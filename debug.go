@@ -0,0 +1,87 @@
+package tart
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// registryPasswordPattern matches a registry password passed through the
+// environment, should it ever appear in output.
+var registryPasswordPattern = regexp.MustCompile(`(TART_REGISTRY_PASSWORD=)\S+`)
+
+// redact removes known secrets, VNC passwords and registry passwords,
+// from text written to the debug log.
+func redact(text string) string {
+	text = registryPasswordPattern.ReplaceAllString(text, "${1}REDACTED")
+	lines := strings.SplitAfter(text, "\n")
+	for i, line := range lines {
+		lines[i] = redactVNC(line)
+	}
+	return strings.Join(lines, "")
+}
+
+// ShellCommand renders the tart command with the given arguments, such as
+// those returned by the Plan methods, as a line that can be pasted into a
+// shell to reproduce it, prefixed with TART_HOME when ConfigDir is set.
+// Registry passwords are redacted.
+func (t *Tart) ShellCommand(args ...string) string {
+	return t.shellCommand(nil, args)
+}
+
+// shellCommand renders a tart command run with additional environment
+// variables as a shell line, redacting registry passwords.
+func (t *Tart) shellCommand(env []string, args []string) string {
+	if t.ConfigDir != "" {
+		env = append([]string{"TART_HOME=" + t.ConfigDir}, env...)
+	}
+	var words []string
+	for _, kv := range env {
+		key, value, _ := strings.Cut(kv, "=")
+		if key == "TART_REGISTRY_PASSWORD" {
+			value = "REDACTED"
+		}
+		words = append(words, key+"="+shellQuote(value))
+	}
+	words = append(words, "tart")
+	for _, arg := range args {
+		words = append(words, shellQuote(arg))
+	}
+	return redact(strings.Join(words, " "))
+}
+
+// debugLog appends a record of a finished command to DebugLogPath, if set.
+// Failures to write the log are ignored so they can't affect the command.
+func (t *Tart) debugLog(args []string, started time.Time, stdout []byte, stderr []byte, err error) {
+	if t.DebugLogPath == "" {
+		return
+	}
+	now := t.clock().Now()
+	status := "0"
+	if err != nil {
+		status = err.Error()
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s tart %s\n", started.UTC().Format(time.RFC3339Nano), strings.Join(args, " "))
+	fmt.Fprintf(&b, "TART_HOME: %s\n", t.EffectiveConfigDir())
+	fmt.Fprintf(&b, "exit: %s (%s)\n", status, now.Sub(started))
+	if len(stdout) > 0 {
+		fmt.Fprintf(&b, "stdout:\n%s\n", strings.TrimRight(string(stdout), "\n"))
+	}
+	if len(stderr) > 0 {
+		fmt.Fprintf(&b, "stderr:\n%s\n", strings.TrimRight(string(stderr), "\n"))
+	}
+	b.WriteString("\n")
+
+	t.logMu.Lock()
+	defer t.logMu.Unlock()
+	f, openErr := os.OpenFile(t.resolvePath(t.DebugLogPath), os.O_WRONLY|os.O_CREATE|os.O_APPEND, t.fileMode())
+	if openErr != nil {
+		return
+	}
+	defer f.Close()
+	f.WriteString(redact(b.String()))
+}
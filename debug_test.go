@@ -0,0 +1,89 @@
+package tart
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDebugLog(t *testing.T) {
+	f := newFakeTart(t, `
+case "$1" in
+get) echo "vnc://:s3cret@127.0.0.1:5900"; echo "warming up" >&2; exit 0;;
+delete) echo "VM \"$2\" does not exist" >&2; exit 1;;
+esac`)
+	f.clk = newFakeClock()
+	f.DebugLogPath = "debug.log"
+	f.WorkDir = f.dir
+	f.run("get", "vm")
+	f.run("delete", "vm")
+
+	data, err := os.ReadFile(filepath.Join(f.dir, "debug.log"))
+	if err != nil {
+		t.Fatalf("reading the debug log: %v", err)
+	}
+	log := string(data)
+	for _, want := range []string{
+		"2024-01-01T00:00:00Z tart get vm\n",
+		"TART_HOME: " + f.ConfigDir + "\n",
+		"exit: 0 (0s)\n",
+		"stdout:\nvnc://:REDACTED@127.0.0.1:5900\n",
+		"stderr:\nwarming up\n",
+		"2024-01-01T00:00:00Z tart delete vm\n",
+		"exit: exit status 1 (0s)\n",
+		"stderr:\nVM \"vm\" does not exist\n",
+	} {
+		if !strings.Contains(log, want) {
+			t.Errorf("debug log is missing %q:\n%s", want, log)
+		}
+	}
+	if strings.Contains(log, "s3cret") {
+		t.Errorf("VNC password leaked into the debug log:\n%s", log)
+	}
+}
+
+func TestDebugLogUnset(t *testing.T) {
+	f := newFakeTart(t, "")
+	f.WorkDir = f.dir
+	if _, err := f.run("list"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(f.dir, "debug.log")); !os.IsNotExist(err) {
+		t.Errorf("debug log written without DebugLogPath: %v", err)
+	}
+}
+
+func TestShellCommand(t *testing.T) {
+	creds := (&Credentials{Username: "bot", Password: "hunter2"}).env()
+	tests := []struct {
+		name      string
+		configDir string
+		env       []string
+		args      []string
+		want      string
+	}{
+		{name: "plain", args: []string{"list"}, want: "tart list"},
+		{name: "quoted", args: []string{"clone", "base", "my vm"}, want: "tart clone base 'my vm'"},
+		{name: "config dir", configDir: "/tmp/tart home", args: []string{"list"}, want: "TART_HOME='/tmp/tart home' tart list"},
+		{
+			name: "credentials",
+			env:  creds,
+			args: []string{"pull", "ghcr.io/org/img"},
+			want: "TART_REGISTRY_USERNAME=bot TART_REGISTRY_PASSWORD=REDACTED tart pull ghcr.io/org/img",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tart := &Tart{ConfigDir: tt.configDir}
+			if got := tart.shellCommand(tt.env, tt.args); got != tt.want {
+				t.Errorf("shellCommand = %s, want %s", got, tt.want)
+			}
+			if tt.env == nil {
+				if got := tart.ShellCommand(tt.args...); got != tt.want {
+					t.Errorf("ShellCommand = %s, want %s", got, tt.want)
+				}
+			}
+		})
+	}
+}
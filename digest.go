@@ -0,0 +1,98 @@
+package tart
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// digestsFile is the name of the file in the config directory recording the
+// manifest digest of each reference last pulled with PullIfChanged.
+const digestsFile = "go-tart-digests.json"
+
+// digestTimeout bounds the registry query made by PullIfChanged.
+const digestTimeout = 30 * time.Second
+
+// PullIfChanged pulls an image unless the registry reports the same
+// manifest digest as when it was last pulled with PullIfChanged and the
+// image is still present locally. If the remote digest can't be determined,
+// for example because the registry requires credentials, the image is
+// pulled anyway. Like InspectRemote, a reference without a registry host is
+// qualified with the instance's Host.
+// It returns whether the image was pulled, and an error if the pull fails.
+func (t *Tart) PullIfChanged(ref string) (bool, error) {
+	ref = t.qualifyReference(ref)
+	digest, _ := t.remoteDigest(ref)
+	if digest != "" {
+		digests, err := t.readDigests()
+		if err != nil {
+			return false, err
+		}
+		if digests[ref] == digest {
+			if _, err := t.Path(ref); err == nil {
+				return false, nil
+			}
+		}
+	}
+	if err := t.PullWithOptions(ref, PullOptions{}); err != nil {
+		return false, err
+	}
+	if digest != "" {
+		if err := t.recordDigest(ref, digest); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// remoteDigest queries the registry for the manifest digest of a reference.
+func (t *Tart) remoteDigest(ref string) (string, error) {
+	r, err := parseReference(ref)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), digestTimeout)
+	defer cancel()
+	c := &registryClient{http: http.DefaultClient}
+	return c.digest(ctx, r)
+}
+
+// readDigests reads the recorded digests.
+func (t *Tart) readDigests() (map[string]string, error) {
+	digests := map[string]string{}
+	data, err := os.ReadFile(filepath.Join(t.EffectiveConfigDir(), digestsFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return digests, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pulled digests: %w", err)
+	}
+	if err := json.Unmarshal(data, &digests); err != nil {
+		return nil, fmt.Errorf("failed to parse pulled digests: %w", err)
+	}
+	return digests, nil
+}
+
+// recordDigest records the digest last pulled for a reference.
+func (t *Tart) recordDigest(ref string, digest string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	digests, err := t.readDigests()
+	if err != nil {
+		return err
+	}
+	digests[ref] = digest
+	data, err := json.MarshalIndent(digests, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode pulled digests: %w", err)
+	}
+	if err := t.writeFile(filepath.Join(t.EffectiveConfigDir(), digestsFile), data); err != nil {
+		return fmt.Errorf("failed to write pulled digests: %w", err)
+	}
+	return nil
+}
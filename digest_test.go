@@ -0,0 +1,61 @@
+package tart
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPullIfChangedQualifiesReference(t *testing.T) {
+	var queries atomic.Int32
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead || r.URL.Path != "/v2/org/image/manifests/latest" {
+			http.NotFound(w, r)
+			return
+		}
+		queries.Add(1)
+		w.Header().Set("Docker-Content-Digest", "sha256:abc")
+	}))
+	defer srv.Close()
+	transport := http.DefaultClient.Transport
+	http.DefaultClient.Transport = srv.Client().Transport
+	defer func() { http.DefaultClient.Transport = transport }()
+
+	f := newFakeTart(t, "")
+	f.Host = strings.TrimPrefix(srv.URL, "https://")
+	qualified := f.Host + "/org/image"
+	cached := filepath.Join(f.ConfigDir, "cache", "OCIs", filepath.FromSlash(qualified), "latest")
+	if err := os.MkdirAll(cached, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		wantPulled bool
+	}{
+		{"first pull", true},
+		{"unchanged digest", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pulled, err := f.PullIfChanged("org/image")
+			if err != nil {
+				t.Fatalf("PullIfChanged: %v", err)
+			}
+			if pulled != tt.wantPulled {
+				t.Errorf("pulled = %v, want %v", pulled, tt.wantPulled)
+			}
+		})
+	}
+	if n := queries.Load(); n != 2 {
+		t.Errorf("registry was queried %d times, want 2", n)
+	}
+	pulls := f.commandCalls(t, "pull")
+	if len(pulls) != 1 || pulls[0][1] != qualified {
+		t.Errorf("pull calls = %q, want one pull of %s", pulls, qualified)
+	}
+}
@@ -0,0 +1,55 @@
+package tart
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DisplayPreset represents a named display resolution.
+type DisplayPreset struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// displayPresets maps preset names to resolutions.
+var displayPresets = map[string]DisplayPreset{
+	"720p":   {Width: 1280, Height: 720},
+	"1080p":  {Width: 1920, Height: 1080},
+	"1440p":  {Width: 2560, Height: 1440},
+	"4k":     {Width: 3840, Height: 2160},
+	"retina": {Width: 2880, Height: 1800},
+}
+
+// DisplayPresets returns the names of the available display presets, sorted.
+func DisplayPresets() []string {
+	names := make([]string, 0, len(displayPresets))
+	for name := range displayPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupDisplayPreset returns the resolution of a named display preset.
+// It returns an error if the preset doesn't exist.
+func LookupDisplayPreset(preset string) (DisplayPreset, error) {
+	p, ok := displayPresets[strings.ToLower(preset)]
+	if !ok {
+		return p, fmt.Errorf("invalid display preset: %s (valid presets: %s)", preset, strings.Join(DisplayPresets(), ", "))
+	}
+	return p, nil
+}
+
+// ApplyDisplayPreset sets a VM's display to the resolution of a named preset.
+// It returns an error if the preset doesn't exist or the configuration update fails.
+func (t *Tart) ApplyDisplayPreset(name string, preset string) error {
+	p, err := LookupDisplayPreset(preset)
+	if err != nil {
+		return err
+	}
+	var config VMConfig
+	config.Display.Width = p.Width
+	config.Display.Height = p.Height
+	return t.SetConfig(name, config)
+}
@@ -0,0 +1,55 @@
+package tart
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLookupDisplayPreset(t *testing.T) {
+	tests := []struct {
+		preset  string
+		want    DisplayPreset
+		wantErr bool
+	}{
+		{preset: "1080p", want: DisplayPreset{Width: 1920, Height: 1080}},
+		{preset: "4K", want: DisplayPreset{Width: 3840, Height: 2160}},
+		{preset: "retina", want: DisplayPreset{Width: 2880, Height: 1800}},
+		{preset: "8k", wantErr: true},
+		{preset: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.preset, func(t *testing.T) {
+			got, err := LookupDisplayPreset(tt.preset)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LookupDisplayPreset error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("LookupDisplayPreset = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyDisplayPreset(t *testing.T) {
+	tests := []struct {
+		name    string
+		preset  string
+		want    [][]string
+		wantErr bool
+	}{
+		{name: "valid", preset: "720p", want: [][]string{{"set", "vm", "--display", "1280x720"}}},
+		{name: "invalid", preset: "huge", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeTart(t, "")
+			err := f.ApplyDisplayPreset("vm", tt.preset)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ApplyDisplayPreset error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := f.commandCalls(t, "set"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("set calls = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
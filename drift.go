@@ -0,0 +1,76 @@
+package tart
+
+// ConfigDrift compares a VM's current configuration with a desired one and
+// returns the names of the fields that differ. Zero-valued fields in desired
+// are treated as "don't care", as is a MACAddress of "random". Some of the
+// fields reported, such as OS and the minimums, can't be changed by
+// SetConfig.
+// It returns an error if the current configuration can't be read.
+func (t *Tart) ConfigDrift(name string, desired VMConfig) ([]string, error) {
+	current, err := t.Config(name)
+	if err != nil {
+		return nil, err
+	}
+	return configDrift(current, desired), nil
+}
+
+// configDrift returns the names of the fields set in desired that differ
+// from current.
+func configDrift(current VMConfig, desired VMConfig) []string {
+	var drift []string
+	if desired.Version != 0 && desired.Version != current.Version {
+		drift = append(drift, "Version")
+	}
+	if desired.OS != "" && desired.OS != current.OS {
+		drift = append(drift, "OS")
+	}
+	if desired.Arch != "" && desired.Arch != current.Arch {
+		drift = append(drift, "Arch")
+	}
+	if desired.CPUCountMin != 0 && desired.CPUCountMin != current.CPUCountMin {
+		drift = append(drift, "CPUCountMin")
+	}
+	if desired.CPUCount != 0 && desired.CPUCount != current.CPUCount {
+		drift = append(drift, "CPUCount")
+	}
+	if desired.MemorySizeMin != 0 && desired.MemorySizeMin != current.MemorySizeMin {
+		drift = append(drift, "MemorySizeMin")
+	}
+	if desired.MemorySize != 0 && desired.MemorySize != current.MemorySize {
+		drift = append(drift, "MemorySize")
+	}
+	if desired.MACAddress != "" && desired.MACAddress != "random" {
+		want, wantErr := canonicalMAC(desired.MACAddress)
+		have, haveErr := canonicalMAC(current.MACAddress)
+		if wantErr != nil || haveErr != nil || want != have {
+			drift = append(drift, "MACAddress")
+		}
+	}
+	if desired.Display.Width != 0 && desired.Display.Width != current.Display.Width {
+		drift = append(drift, "Display.Width")
+	}
+	if desired.Display.Height != 0 && desired.Display.Height != current.Display.Height {
+		drift = append(drift, "Display.Height")
+	}
+	return drift
+}
+
+// splitDrift separates the drifted fields SetConfig can apply from those it
+// can't. A display size is only applied when both dimensions are set.
+func splitDrift(desired VMConfig, drift []string) (settable []string, unsettable []string) {
+	for _, field := range drift {
+		switch field {
+		case "CPUCount", "MemorySize", "MACAddress":
+			settable = append(settable, field)
+		case "Display.Width", "Display.Height":
+			if desired.Display.Width > 0 && desired.Display.Height > 0 {
+				settable = append(settable, field)
+			} else {
+				unsettable = append(unsettable, field)
+			}
+		default:
+			unsettable = append(unsettable, field)
+		}
+	}
+	return settable, unsettable
+}
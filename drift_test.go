@@ -0,0 +1,40 @@
+package tart
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConfigDriftAndSplit(t *testing.T) {
+	var current VMConfig
+	current.OS = "darwin"
+	current.CPUCount = 4
+	current.CPUCountMin = 4
+	current.MemorySize = 8192
+	current.Display.Width = 1024
+	current.Display.Height = 768
+
+	tests := []struct {
+		name           string
+		desired        func(*VMConfig)
+		wantSettable   []string
+		wantUnsettable []string
+	}{
+		{name: "no drift", desired: func(c *VMConfig) { c.CPUCount = 4 }},
+		{name: "cpu and memory", desired: func(c *VMConfig) { c.CPUCount = 8; c.MemorySize = 16384 }, wantSettable: []string{"CPUCount", "MemorySize"}},
+		{name: "os and minimum", desired: func(c *VMConfig) { c.OS = "linux"; c.CPUCountMin = 2 }, wantUnsettable: []string{"OS", "CPUCountMin"}},
+		{name: "full display", desired: func(c *VMConfig) { c.Display.Width = 1920; c.Display.Height = 1080 }, wantSettable: []string{"Display.Width", "Display.Height"}},
+		{name: "width only", desired: func(c *VMConfig) { c.Display.Width = 1920 }, wantUnsettable: []string{"Display.Width"}},
+		{name: "random MAC", desired: func(c *VMConfig) { c.MACAddress = "random" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var desired VMConfig
+			tt.desired(&desired)
+			settable, unsettable := splitDrift(desired, configDrift(current, desired))
+			if !reflect.DeepEqual(settable, tt.wantSettable) || !reflect.DeepEqual(unsettable, tt.wantUnsettable) {
+				t.Errorf("got settable %v, unsettable %v; want %v, %v", settable, unsettable, tt.wantSettable, tt.wantUnsettable)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package tart
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// VMSpec describes the desired state of a VM for EnsureVM.
+// Exactly one source must be set: either Create (from an IPSW, as an empty
+// Linux VM or from a disk image) or CloneFrom (a local VM or a remote image).
+type VMSpec struct {
+	Create    CreateOptions `json:"create"`
+	CloneFrom string        `json:"cloneFrom"`
+	Clone     CloneOptions  `json:"clone"`
+	Config    VMConfig      `json:"config"`
+}
+
+// validate checks that the spec has exactly one source.
+func (s VMSpec) validate() error {
+	fromCreate := s.Create.hasSource()
+	fromClone := s.CloneFrom != ""
+	if fromCreate && fromClone {
+		return errors.New("spec must not set both a create source and a clone source")
+	}
+	if !fromCreate && !fromClone {
+		return errors.New("spec must set either a create source or a clone source")
+	}
+	return nil
+}
+
+// Constants representing the outcomes of EnsureVM.
+const (
+	// EnsureCreated means the VM was absent and has been created or cloned.
+	EnsureCreated = "created"
+	// EnsureUpdated means the VM existed and its config has been changed.
+	EnsureUpdated = "updated"
+	// EnsureUnchanged means the VM already matched the spec.
+	EnsureUnchanged = "unchanged"
+)
+
+// EnsureVM makes sure a VM matching the spec exists.
+// If the VM is absent it is created or cloned from the spec's source and
+// the spec's config is applied with SetConfig. If it's present, the config
+// is only applied when ConfigDrift reports a difference, so calling it again
+// with the same spec is a no-op. A MACAddress of "random" only takes effect
+// when the VM is created.
+// It returns EnsureCreated, EnsureUpdated or EnsureUnchanged, and an error
+// if the spec is invalid, the VM differs from it in fields SetConfig can't
+// change, or any step fails.
+func (t *Tart) EnsureVM(name string, spec VMSpec) (string, error) {
+	if err := spec.validate(); err != nil {
+		return "", fmt.Errorf("invalid VM spec: %w", err)
+	}
+	exists, err := t.Exists(name)
+	if err != nil {
+		return "", err
+	}
+	outcome := EnsureUpdated
+	if !exists {
+		if spec.CloneFrom != "" {
+			err = t.Clone(spec.CloneFrom, name, spec.Clone)
+		} else {
+			err = t.Create(name, spec.Create)
+		}
+		if err != nil {
+			return "", err
+		}
+		outcome = EnsureCreated
+	}
+	config := spec.Config
+	if outcome != EnsureCreated {
+		drift, err := t.ConfigDrift(name, config)
+		if err != nil {
+			return "", err
+		}
+		settable, unsettable := splitDrift(config, drift)
+		if len(unsettable) > 0 {
+			return "", fmt.Errorf("VM %s differs from the spec in fields SetConfig can't change: %s", name, strings.Join(unsettable, ", "))
+		}
+		if len(settable) == 0 {
+			return EnsureUnchanged, nil
+		}
+		if config.MACAddress == "random" {
+			config.MACAddress = ""
+		}
+	}
+	if err := t.SetConfig(name, config); err != nil {
+		return outcome, err
+	}
+	return outcome, nil
+}
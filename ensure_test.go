@@ -0,0 +1,45 @@
+package tart
+
+import (
+	"testing"
+)
+
+func TestEnsureVMOutcomes(t *testing.T) {
+	tests := []struct {
+		name    string
+		exists  bool
+		desired func(*VMConfig)
+		want    string
+		wantSet bool
+		wantErr bool
+	}{
+		{name: "absent", desired: func(c *VMConfig) {}, want: EnsureCreated},
+		{name: "matching", exists: true, desired: func(c *VMConfig) { c.CPUCount = 4 }, want: EnsureUnchanged},
+		{name: "drifted", exists: true, desired: func(c *VMConfig) { c.CPUCount = 8 }, want: EnsureUpdated, wantSet: true},
+		{name: "unsettable drift", exists: true, desired: func(c *VMConfig) { c.OS = "linux" }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeTart(t, "")
+			if tt.exists {
+				f.addVM(t, "vm", `{"os":"darwin","cpuCount":4}`)
+				f.setList(t, `[{"name":"vm","state":"stopped","source":"local"}]`)
+			}
+			var config VMConfig
+			tt.desired(&config)
+			got, err := f.EnsureVM("vm", VMSpec{CloneFrom: "base", Config: config})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EnsureVM error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("EnsureVM = %q, want %q", got, tt.want)
+			}
+			if set := len(f.commandCalls(t, "set")) > 0; set != tt.wantSet {
+				t.Errorf("set called = %v, want %v", set, tt.wantSet)
+			}
+			if clones := len(f.commandCalls(t, "clone")); clones > 0 == tt.exists {
+				t.Errorf("clone called %d times with exists = %v", clones, tt.exists)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package tart
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNoMACAddress is returned when a VM has no MAC address assigned yet.
+var ErrNoMACAddress = errors.New("VM has no MAC address")
+
+// ErrVMNotFound is returned when a VM does not exist.
+var ErrVMNotFound = errors.New("VM does not exist")
+
+// ErrVMAlreadyExists is returned when a VM with the requested name already exists.
+var ErrVMAlreadyExists = errors.New("VM already exists")
+
+// ErrVMNotRunning is returned when an operation needs a running VM but it's stopped.
+var ErrVMNotRunning = errors.New("VM is not running")
+
+// ErrVMAlreadyRunning is returned when running a VM that is already running
+// or being started.
+var ErrVMAlreadyRunning = errors.New("VM is already running")
+
+// ErrUnsupported is returned when the installed Tart doesn't provide a feature.
+var ErrUnsupported = errors.New("not supported by the installed Tart")
+
+// ErrNestedUnsupported is returned when nested virtualization is requested
+// on a host that can't provide it.
+var ErrNestedUnsupported = errors.New("nested virtualization is not supported on this host")
+
+// ErrRosettaUnavailable is returned when a Linux VM is run with Rosetta on a
+// host that doesn't have it installed.
+var ErrRosettaUnavailable = errors.New("Rosetta is not installed on this host")
+
+// ErrNoSuspendState is returned when a VM has no saved suspend state, either
+// because it was never suspended or because it has since been resumed.
+var ErrNoSuspendState = errors.New("VM has no suspend state")
+
+// ErrHostIncompatible is returned when the host doesn't meet the caller's
+// requirements.
+var ErrHostIncompatible = errors.New("host is not compatible")
+
+// ErrNoPullInProgress is returned when cancelling a pull or clone that isn't
+// running.
+var ErrNoPullInProgress = errors.New("no pull in progress")
+
+// ErrIPNotReady is returned when a VM has no IP address yet, typically
+// because it's still booting and hasn't obtained a DHCP lease.
+var ErrIPNotReady = errors.New("VM has no IP address yet")
+
+// ValidationError is returned when options fail validation. It lists every
+// problem found rather than just the first, and errors.Is and errors.As see
+// through to each of them.
+type ValidationError struct {
+	Problems []error
+}
+
+// Error returns all the problems, separated by semicolons.
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		msgs[i] = p.Error()
+	}
+	return "invalid options: " + strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the individual problems.
+func (e *ValidationError) Unwrap() []error {
+	return e.Problems
+}
+
+// validationError returns a *ValidationError for the problems, or nil if
+// there are none.
+func validationError(problems []error) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: problems}
+}
+
+// CommandError is returned when a tart command exits unsuccessfully.
+type CommandError struct {
+	Args   []string
+	Stderr string
+	Err    error
+	// Command is the failed command rendered as a shell line, as by
+	// ShellCommand, for reproducing it manually.
+	Command string
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("command failed: %v, stderr: %s", e.Err, e.Stderr)
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// ConfigDirError is returned when the config directory can't be written to,
+// for example because it's on a read-only mount.
+type ConfigDirError struct {
+	Path string
+	Err  error
+}
+
+func (e *ConfigDirError) Error() string {
+	return fmt.Sprintf("config directory %s is not writable: %v", e.Path, e.Err)
+}
+
+func (e *ConfigDirError) Unwrap() error {
+	return e.Err
+}
+
+// StopTimeoutError is returned when a VM doesn't finish shutting down
+// before the caller's context is done.
+type StopTimeoutError struct {
+	Name string
+	Err  error
+}
+
+func (e *StopTimeoutError) Error() string {
+	return fmt.Sprintf("VM %s did not stop in time: %v", e.Name, e.Err)
+}
+
+func (e *StopTimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// commandStderr returns the stderr of a failed tart command, if err wraps
+// a *CommandError.
+func commandStderr(err error) string {
+	var cmdErr *CommandError
+	if errors.As(err, &cmdErr) {
+		return cmdErr.Stderr
+	}
+	return ""
+}
@@ -0,0 +1,74 @@
+package tart
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNotFoundErrors(t *testing.T) {
+	f := newFakeTart(t, `
+if [ "$1" = delete ]; then
+	echo "Error: the specified VM \"$2\" does not exist" >&2
+	exit 1
+fi
+case "$2" in
+--help) exit 0;;
+esac`)
+	if err := f.Delete("missing"); !errors.Is(err, ErrVMNotFound) {
+		t.Errorf("Delete = %v, want ErrVMNotFound", err)
+	}
+	if err := f.Run("missing", RunOptions{}); !errors.Is(err, ErrVMNotFound) {
+		t.Errorf("Run = %v, want ErrVMNotFound", err)
+	}
+	if runs := f.commandCalls(t, "run"); len(runs) != 1 || runs[0][1] != "--help" {
+		t.Errorf("run calls = %v, want only the help probe", runs)
+	}
+}
+
+func TestValidationErrorCollectsProblems(t *testing.T) {
+	tests := []struct {
+		name     string
+		validate func() error
+		problems int
+	}{
+		{name: "valid run", validate: RunOptions{}.Validate},
+		{
+			name: "run with network and dir problems",
+			validate: RunOptions{
+				NetBridged: "en0",
+				NetHost:    true,
+				Dir:        []DirMount{{Name: "src"}},
+			}.Validate,
+			problems: 2,
+		},
+		{name: "valid create", validate: CreateOptions{Linux: true}.Validate},
+		{
+			name:     "create with source and size problems",
+			validate: CreateOptions{Linux: true, FromDisk: "/nonexistent/disk.img", DiskSize: -1}.Validate,
+			problems: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.validate()
+			if tt.problems == 0 {
+				if err != nil {
+					t.Errorf("Validate = %v, want nil", err)
+				}
+				return
+			}
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Validate = %v, want a *ValidationError", err)
+			}
+			if len(verr.Problems) != tt.problems {
+				t.Errorf("Validate found %d problems, want %d: %v", len(verr.Problems), tt.problems, err)
+			}
+			for _, problem := range verr.Problems {
+				if !errors.Is(err, problem) {
+					t.Errorf("problem %v isn't unwrapped", problem)
+				}
+			}
+		})
+	}
+}
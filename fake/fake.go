@@ -0,0 +1,404 @@
+// Package fake provides an in-memory implementation of tart.Hypervisor for
+// testing code built on go-tart without a Tart binary.
+package fake
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	tart "github.com/joshryandavis/go-tart"
+)
+
+// Call records a method called on a fake Tart and the VM or image it was
+// called for.
+type Call struct {
+	Method string
+	Name   string
+}
+
+// Tart is an in-memory tart.Hypervisor. VMs are created, cloned and pulled
+// instantly, running a VM assigns it an IP address, and the errors that
+// mirror Tart's (tart.ErrVMNotFound, tart.ErrVMAlreadyExists,
+// tart.ErrVMNotRunning and tart.ErrIPNotReady) are returned where the real
+// implementation would return them. It's safe for concurrent use.
+type Tart struct {
+	// Errors, if set for a method name such as "Run", makes every call to
+	// that method fail with the error before doing anything.
+	Errors map[string]error
+	// DefaultConfig is the configuration given to created VMs and to VMs
+	// cloned from pulled images.
+	DefaultConfig tart.VMConfig
+
+	mu      sync.Mutex
+	vms     map[string]*vm
+	images  map[string]bool
+	calls   []Call
+	nextIP  int
+	nextMAC int
+}
+
+type vm struct {
+	state  tart.VMState
+	config tart.VMConfig
+	ip     string
+}
+
+var _ tart.Hypervisor = (*Tart)(nil)
+
+// New creates an empty fake with a DefaultConfig of 4 CPUs and 8 GB of
+// memory. The zero value is also usable, with a zero DefaultConfig.
+func New() *Tart {
+	return &Tart{
+		DefaultConfig: tart.VMConfig{CPUCount: 4, MemorySize: 8192},
+		vms:           map[string]*vm{},
+		images:        map[string]bool{},
+	}
+}
+
+// AddVM adds a stopped local VM with the given configuration.
+func (f *Tart) AddVM(name string, config tart.VMConfig) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.init()
+	f.vms[name] = newVM(name, config)
+}
+
+// AddImage makes a remote image available for Clone as if it had been pulled.
+func (f *Tart) AddImage(ref string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.init()
+	f.images[ref] = true
+}
+
+// Calls returns the calls made so far, in order.
+func (f *Tart) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+func newVM(name string, config tart.VMConfig) *vm {
+	return &vm{
+		state:  tart.VMState{Name: name, Source: tart.SourceLocal, State: tart.StateStopped},
+		config: config,
+	}
+}
+
+// begin records a call and returns the error injected for the method, if
+// any. The caller must hold f.mu.
+func (f *Tart) begin(method string, name string) error {
+	f.init()
+	f.calls = append(f.calls, Call{Method: method, Name: name})
+	return f.Errors[method]
+}
+
+// init creates the fake's maps if it's a zero value. The caller must hold
+// f.mu.
+func (f *Tart) init() {
+	if f.vms == nil {
+		f.vms = map[string]*vm{}
+	}
+	if f.images == nil {
+		f.images = map[string]bool{}
+	}
+}
+
+// lookup returns a VM or an error wrapping tart.ErrVMNotFound. The caller
+// must hold f.mu.
+func (f *Tart) lookup(name string) (*vm, error) {
+	v, ok := f.vms[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", tart.ErrVMNotFound, name)
+	}
+	return v, nil
+}
+
+// randomMAC returns a MAC address no other VM has been given. The caller
+// must hold f.mu.
+func (f *Tart) randomMAC() string {
+	f.nextMAC++
+	return fmt.Sprintf("7e:00:00:00:%02x:%02x", f.nextMAC>>8&0xff, f.nextMAC&0xff)
+}
+
+// Create adds a stopped VM with the default configuration.
+func (f *Tart) Create(name string, options tart.CreateOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin("Create", name); err != nil {
+		return err
+	}
+	if _, ok := f.vms[name]; ok {
+		return fmt.Errorf("%w: %s", tart.ErrVMAlreadyExists, name)
+	}
+	f.vms[name] = newVM(name, f.DefaultConfig)
+	return nil
+}
+
+// Clone copies a local VM, or instantiates a pulled image, as a stopped VM.
+func (f *Tart) Clone(sourceName string, newName string, options tart.CloneOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin("Clone", newName); err != nil {
+		return err
+	}
+	if _, ok := f.vms[newName]; ok {
+		return fmt.Errorf("%w: %s", tart.ErrVMAlreadyExists, newName)
+	}
+	config := f.DefaultConfig
+	if src, ok := f.vms[sourceName]; ok {
+		config = src.config
+	} else if !f.images[sourceName] {
+		return fmt.Errorf("%w: %s", tart.ErrVMNotFound, sourceName)
+	}
+	if options.RandomizeMAC {
+		config.MACAddress = f.randomMAC()
+	}
+	f.vms[newName] = newVM(newName, config)
+	return nil
+}
+
+// Delete removes a VM.
+func (f *Tart) Delete(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin("Delete", name); err != nil {
+		return err
+	}
+	if _, err := f.lookup(name); err != nil {
+		return err
+	}
+	delete(f.vms, name)
+	return nil
+}
+
+// Rename renames a stopped VM.
+func (f *Tart) Rename(oldName string, newName string) (tart.VMState, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin("Rename", oldName); err != nil {
+		return tart.VMState{}, err
+	}
+	v, err := f.lookup(oldName)
+	if err != nil {
+		return tart.VMState{}, err
+	}
+	if _, ok := f.vms[newName]; ok {
+		return tart.VMState{}, fmt.Errorf("%w: %s", tart.ErrVMAlreadyExists, newName)
+	}
+	if v.state.State == tart.StateRunning {
+		return tart.VMState{}, fmt.Errorf("VM %s is running", oldName)
+	}
+	delete(f.vms, oldName)
+	v.state.Name = newName
+	f.vms[newName] = v
+	return v.state, nil
+}
+
+// List lists the local VMs, the pulled images, or both, sorted by name.
+func (f *Tart) List(config tart.ListOptions) ([]tart.VMState, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin("List", ""); err != nil {
+		return nil, err
+	}
+	if config.Source != nil && *config.Source != tart.SourceLocal && *config.Source != tart.SourceRemote {
+		return nil, fmt.Errorf("invalid source: %s", *config.Source)
+	}
+	var vms []tart.VMState
+	if config.Source == nil || *config.Source == tart.SourceLocal {
+		for _, v := range f.vms {
+			vms = append(vms, v.state)
+		}
+	}
+	if config.Source == nil || *config.Source == tart.SourceRemote {
+		for ref := range f.images {
+			vms = append(vms, tart.VMState{Name: ref, Source: tart.SourceRemote, State: tart.StateStopped})
+		}
+	}
+	sort.Slice(vms, func(i, j int) bool {
+		if vms[i].Source != vms[j].Source {
+			return vms[i].Source == tart.SourceLocal
+		}
+		return vms[i].Name < vms[j].Name
+	})
+	return vms, nil
+}
+
+// State returns a VM's state, or the zero VMState if it doesn't exist.
+func (f *Tart) State(name string) (tart.VMState, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin("State", name); err != nil {
+		return tart.VMState{}, err
+	}
+	if v, ok := f.vms[name]; ok {
+		return v.state, nil
+	}
+	return tart.VMState{}, nil
+}
+
+// Exists reports whether a local VM exists.
+func (f *Tart) Exists(name string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin("Exists", name); err != nil {
+		return false, err
+	}
+	_, ok := f.vms[name]
+	return ok, nil
+}
+
+// Run marks a VM as running and assigns it an IP address. Like the real Run,
+// it returns once the VM is up.
+func (f *Tart) Run(name string, options tart.RunOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin("Run", name); err != nil {
+		return err
+	}
+	if err := options.Validate(); err != nil {
+		return err
+	}
+	v, err := f.lookup(name)
+	if err != nil {
+		return err
+	}
+	if v.state.State == tart.StateRunning {
+		return fmt.Errorf("%w: %s", tart.ErrVMAlreadyRunning, name)
+	}
+	f.nextIP++
+	v.state.State = tart.StateRunning
+	v.ip = fmt.Sprintf("192.168.64.%d", f.nextIP%253+2)
+	return nil
+}
+
+// Stop marks a running or suspended VM as stopped.
+func (f *Tart) Stop(name string, timeout int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin("Stop", name); err != nil {
+		return err
+	}
+	v, err := f.lookup(name)
+	if err != nil {
+		return err
+	}
+	if v.state.State == tart.StateStopped {
+		return fmt.Errorf("%w: %s", tart.ErrVMNotRunning, name)
+	}
+	v.state.State = tart.StateStopped
+	v.ip = ""
+	return nil
+}
+
+// Suspend marks a running VM as suspended.
+func (f *Tart) Suspend(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin("Suspend", name); err != nil {
+		return err
+	}
+	v, err := f.lookup(name)
+	if err != nil {
+		return err
+	}
+	if v.state.State != tart.StateRunning {
+		return fmt.Errorf("%w: %s", tart.ErrVMNotRunning, name)
+	}
+	v.state.State = tart.StateSuspended
+	v.ip = ""
+	return nil
+}
+
+// IP returns the address assigned to a running VM. The wait and resolver
+// arguments are ignored.
+func (f *Tart) IP(name string, wait int, resolver string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin("IP", name); err != nil {
+		return "", err
+	}
+	v, err := f.lookup(name)
+	if err != nil {
+		return "", err
+	}
+	if v.ip == "" {
+		return "", fmt.Errorf("%w: %s", tart.ErrIPNotReady, name)
+	}
+	return v.ip, nil
+}
+
+// Config returns a VM's configuration.
+func (f *Tart) Config(name string) (tart.VMConfig, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin("Config", name); err != nil {
+		return tart.VMConfig{}, err
+	}
+	v, err := f.lookup(name)
+	if err != nil {
+		return tart.VMConfig{}, err
+	}
+	return v.config, nil
+}
+
+// SetConfig applies the non-zero CPU, memory and display settings to a VM's
+// configuration, along with MACAddress, for which "random" generates a new
+// address.
+func (f *Tart) SetConfig(name string, config tart.VMConfig) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin("SetConfig", name); err != nil {
+		return err
+	}
+	v, err := f.lookup(name)
+	if err != nil {
+		return err
+	}
+	if config.CPUCount > 0 {
+		v.config.CPUCount = config.CPUCount
+	}
+	if config.MemorySize > 0 {
+		v.config.MemorySize = config.MemorySize
+	}
+	if config.Display.Width > 0 && config.Display.Height > 0 {
+		v.config.Display = config.Display
+	}
+	if config.MACAddress != "" && config.MACAddress != "random" {
+		v.config.MACAddress = config.MACAddress
+	}
+	if config.MACAddress == "random" {
+		v.config.MACAddress = f.randomMAC()
+	}
+	return nil
+}
+
+// PullWithOptions makes a remote image available for Clone.
+func (f *Tart) PullWithOptions(name string, options tart.PullOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin("PullWithOptions", name); err != nil {
+		return err
+	}
+	f.images[name] = true
+	return nil
+}
+
+// Push records a push of a local VM to its remote names, making them
+// available for Clone.
+func (f *Tart) Push(name string, options tart.PushOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.begin("Push", name); err != nil {
+		return err
+	}
+	if _, err := f.lookup(name); err != nil {
+		return err
+	}
+	for _, ref := range options.RemoteNames {
+		f.images[ref] = true
+	}
+	return nil
+}
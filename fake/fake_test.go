@@ -0,0 +1,57 @@
+package fake
+
+import (
+	"errors"
+	"testing"
+
+	tart "github.com/joshryandavis/go-tart"
+)
+
+func TestZeroValue(t *testing.T) {
+	var f Tart
+	f.AddImage("ghcr.io/org/image")
+	if err := f.Clone("ghcr.io/org/image", "vm", tart.CloneOptions{}); err != nil {
+		t.Fatalf("Clone: %v", err)
+	}
+	if err := f.PullWithOptions("ghcr.io/org/other", tart.PullOptions{}); err != nil {
+		t.Fatalf("PullWithOptions: %v", err)
+	}
+	var zero Tart
+	zero.AddVM("vm", tart.VMConfig{})
+	if err := (&Tart{}).Create("vm", tart.CreateOptions{Linux: true}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	f := New()
+	if err := f.Run("missing", tart.RunOptions{}); !errors.Is(err, tart.ErrVMNotFound) {
+		t.Errorf("Run = %v, want ErrVMNotFound", err)
+	}
+	if err := f.Delete("missing"); !errors.Is(err, tart.ErrVMNotFound) {
+		t.Errorf("Delete = %v, want ErrVMNotFound", err)
+	}
+	f.AddVM("vm", tart.VMConfig{})
+	if err := f.Run("vm", tart.RunOptions{}); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if err := f.Run("vm", tart.RunOptions{}); !errors.Is(err, tart.ErrVMAlreadyRunning) {
+		t.Errorf("second Run = %v, want ErrVMAlreadyRunning", err)
+	}
+	if err := f.Stop("vm", 0); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if err := f.Stop("vm", 0); !errors.Is(err, tart.ErrVMNotRunning) {
+		t.Errorf("second Stop = %v, want ErrVMNotRunning", err)
+	}
+	if _, err := f.IP("vm", 0, ""); !errors.Is(err, tart.ErrIPNotReady) {
+		t.Errorf("IP of a stopped VM = %v, want ErrIPNotReady", err)
+	}
+	f.Errors = map[string]error{"Delete": errors.New("boom")}
+	if err := f.Delete("vm"); err == nil || err.Error() != "boom" {
+		t.Errorf("Delete with an injected error = %v", err)
+	}
+	if calls := f.Calls(); len(calls) == 0 || calls[len(calls)-1] != (Call{Method: "Delete", Name: "vm"}) {
+		t.Errorf("calls = %v", calls)
+	}
+}
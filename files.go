@@ -0,0 +1,60 @@
+package tart
+
+import (
+	"os"
+)
+
+// defaultFileMode is the mode of files this package writes when the
+// instance's FileMode is zero: readable and writable by the owner only.
+const defaultFileMode os.FileMode = 0600
+
+// fileMode returns the mode for files this package writes.
+func (t *Tart) fileMode() os.FileMode {
+	if t.FileMode != 0 {
+		return t.FileMode.Perm()
+	}
+	return defaultFileMode
+}
+
+// writeFile writes data to path atomically, through a temporary file that
+// is renamed into place, with the instance's file mode regardless of the
+// process's umask.
+func (t *Tart) writeFile(path string, data []byte) error {
+	return writeFileMode(path, data, t.fileMode())
+}
+
+// writeFileMode is like writeFile, but with an explicit mode.
+func writeFileMode(path string, data []byte, mode os.FileMode) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, mode); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, mode); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// createFile creates a new file at path with the instance's file mode
+// regardless of the process's umask. With exclusive set it fails if the file
+// already exists; otherwise an existing file is truncated.
+func (t *Tart) createFile(path string, exclusive bool) (*os.File, error) {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if exclusive {
+		flags |= os.O_EXCL
+	}
+	f, err := os.OpenFile(path, flags, t.fileMode())
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Chmod(t.fileMode()); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,68 @@
+package tart
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileMode(t *testing.T) {
+	tests := []struct {
+		name string
+		mode os.FileMode
+		want os.FileMode
+	}{
+		{name: "default", want: 0600},
+		{name: "group readable", mode: 0640, want: 0640},
+		// Wider than a typical umask allows, so it must be applied explicitly
+		{name: "world writable", mode: 0666, want: 0666},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeTart(t, "")
+			f.FileMode = tt.mode
+			f.addVM(t, "vm", `{}`)
+			dir := t.TempDir()
+
+			written := filepath.Join(dir, "written")
+			if err := f.writeFile(written, []byte("data")); err != nil {
+				t.Fatal(err)
+			}
+			created := filepath.Join(dir, "created")
+			out, err := f.createFile(created, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out.Close()
+			spec := filepath.Join(dir, "spec.json")
+			if err := f.ExportSpec("vm", spec); err != nil {
+				t.Fatal(err)
+			}
+			if err := f.metadata().Set("vm", map[string]string{"owner": "ci"}); err != nil {
+				t.Fatal(err)
+			}
+			sidecar := FileMetadataStore{Dir: f.ConfigDir}.path("vm")
+
+			for _, path := range []string{written, created, spec, sidecar} {
+				info, err := os.Stat(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := info.Mode().Perm(); got != tt.want {
+					t.Errorf("%s has mode %v, want %v", filepath.Base(path), got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateFileExclusive(t *testing.T) {
+	f := newFakeTart(t, "")
+	path := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.createFile(path, true); !os.IsExist(err) {
+		t.Errorf("createFile exclusive over an existing file = %v, want ErrExist", err)
+	}
+}
@@ -0,0 +1,190 @@
+package tart
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// fleetConcurrency is the number of VMs operated on at once by fleet methods
+// that don't take an explicit concurrency.
+const fleetConcurrency = 4
+
+// forEach calls fn for each name with at most concurrency calls in flight.
+// Names listed more than once are only passed to fn once, so duplicates
+// neither race on the same VM nor overwrite each other's results. Names not
+// yet started when the context is done get the context's error.
+// It returns the error from each call keyed by name.
+func forEach(ctx context.Context, names []string, concurrency int, fn func(name string) error) map[string]error {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	results := make(map[string]error, len(names))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		select {
+		case <-ctx.Done():
+			mu.Lock()
+			results[name] = ctx.Err()
+			mu.Unlock()
+			continue
+		case sem <- struct{}{}:
+		}
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			err := fn(name)
+			mu.Lock()
+			results[name] = err
+			mu.Unlock()
+		}(name)
+	}
+	wg.Wait()
+	return results
+}
+
+// DeleteFleet stops and deletes each of the named VMs.
+// Running VMs are stopped gracefully with the given timeout before being
+// deleted, and VMs that no longer exist are skipped. Names listed more than
+// once are only deleted once.
+// It returns the result for each name, and an error if the VMs can't be listed.
+func (t *Tart) DeleteFleet(names []string, timeout int) (map[string]error, error) {
+	vms, err := t.List(ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list local VMs: %w", err)
+	}
+	states := make(map[string]VMState, len(vms))
+	for _, vm := range vms {
+		states[vm.Name] = vm
+	}
+	results := forEach(context.Background(), names, fleetConcurrency, func(name string) error {
+		s, ok := states[name]
+		if !ok {
+			return nil
+		}
+		if s.State == StateRunning {
+			// The VM may have stopped on its own since it was listed.
+			if err := t.StopIfRunning(name, timeout); err != nil {
+				return err
+			}
+		}
+		return t.Delete(name)
+	})
+	return results, nil
+}
+
+// ExportSpec names a VM to export and the path of the .tvm file to write.
+type ExportSpec struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// ExportAll exports each of the specified VMs with at most concurrency
+// exports in flight, for example for nightly backups. Failed exports don't
+// leave partial files behind.
+// It returns the result for each VM name, and an error if the specs are
+// invalid.
+func (t *Tart) ExportAll(specs []ExportSpec, concurrency int) (map[string]error, error) {
+	return t.ExportAllContext(context.Background(), specs, concurrency)
+}
+
+// ExportAllContext is like ExportAll, but cancelling the context aborts
+// in-flight exports and skips those not yet started.
+// It returns the result for each VM name, and an error if the specs are
+// invalid or the context was done before all exports completed.
+func (t *Tart) ExportAllContext(ctx context.Context, specs []ExportSpec, concurrency int) (map[string]error, error) {
+	paths := make(map[string]string, len(specs))
+	names := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		if spec.Name == "" || spec.Path == "" {
+			return nil, errors.New("invalid export spec: name and path are required")
+		}
+		if _, ok := paths[spec.Name]; ok {
+			return nil, fmt.Errorf("invalid export spec: VM %s is listed twice", spec.Name)
+		}
+		paths[spec.Name] = spec.Path
+		names = append(names, spec.Name)
+	}
+	results := forEach(ctx, names, concurrency, func(name string) error {
+		return t.ExportContext(ctx, name, paths[name])
+	})
+	return results, ctx.Err()
+}
+
+// PullAll pulls each of the referenced images with at most concurrency pulls
+// in flight, for example to warm the cache on a build machine. References
+// listed more than once are only pulled once.
+// It returns the result for each reference.
+func (t *Tart) PullAll(refs []string, concurrency int, insecure bool) (map[string]error, error) {
+	return t.PullAllContext(context.Background(), refs, concurrency, insecure)
+}
+
+// PullAllContext is like PullAll, but cancelling the context aborts in-flight
+// pulls and skips those not yet started.
+// It returns the result for each reference, and the context's error if it was
+// done before all pulls completed.
+func (t *Tart) PullAllContext(ctx context.Context, refs []string, concurrency int, insecure bool) (map[string]error, error) {
+	results := forEach(ctx, refs, concurrency, func(ref string) error {
+		return t.PullContext(ctx, ref, PullOptions{Insecure: insecure})
+	})
+	return results, ctx.Err()
+}
+
+// SetConfigAll applies the same configuration to each of the named VMs, for
+// example to roll out a memory bump across a pool. VMs whose fields that
+// SetConfig can change already match, as ConfigDrift sees it, are skipped.
+// Tart only reads a VM's configuration when it starts, so running VMs are
+// stopped first with Tart's default timeout and left stopped; each change is
+// then applied with SetConfigAtomic. Names listed more than once are only
+// updated once.
+// It returns the result for each name, and an error if the VMs can't be listed.
+func (t *Tart) SetConfigAll(names []string, config VMConfig) (map[string]error, error) {
+	return t.SetConfigAllContext(context.Background(), names, config)
+}
+
+// SetConfigAllContext is like SetConfigAll, but the context bounds waiting for
+// each running VM to stop, as does DefaultTimeout when the context has no
+// deadline, and VMs not yet started when it's done are skipped.
+// It returns the result for each name, and an error if the VMs can't be
+// listed or the context was done before all VMs were updated.
+func (t *Tart) SetConfigAllContext(ctx context.Context, names []string, config VMConfig) (map[string]error, error) {
+	states, err := t.States(names)
+	if err != nil {
+		return nil, err
+	}
+	results := forEach(ctx, names, fleetConcurrency, func(name string) error {
+		s, ok := states[name]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrVMNotFound, name)
+		}
+		drift, err := t.ConfigDrift(name, config)
+		if err != nil {
+			return err
+		}
+		if settable, _ := splitDrift(config, drift); len(settable) == 0 {
+			return nil
+		}
+		if s.State == StateRunning {
+			stopCtx := ctx
+			if _, ok := ctx.Deadline(); !ok && t.DefaultTimeout > 0 {
+				var cancel context.CancelFunc
+				stopCtx, cancel = context.WithTimeout(ctx, t.DefaultTimeout)
+				defer cancel()
+			}
+			if err := t.StopAndWait(stopCtx, name, 0); err != nil {
+				return err
+			}
+		}
+		return t.SetConfigAtomic(name, config)
+	})
+	return results, ctx.Err()
+}
@@ -0,0 +1,229 @@
+package tart
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetConfigAll(t *testing.T) {
+	f := newFakeTart(t, `
+case "$1" in
+stop)
+	touch "$FAKE_TART_DIR/stopped-$2"
+	exit 0;;
+list)
+	state=running
+	[ -f "$FAKE_TART_DIR/stopped-busy" ] && state=stopped
+	echo '[{"name":"same","state":"running"},{"name":"other-os","state":"running"},{"name":"busy","state":"'$state'"},{"name":"idle","state":"stopped"}]'
+	exit 0;;
+esac`)
+	f.clk = newFakeClock()
+	f.addVM(t, "same", `{"os":"darwin","memorySize":8589934592}`)
+	f.addVM(t, "other-os", `{"os":"linux","memorySize":8589934592}`)
+	f.addVM(t, "busy", `{"os":"darwin","memorySize":4294967296}`)
+	f.addVM(t, "idle", `{"os":"darwin","memorySize":4294967296}`)
+	config := VMConfig{OS: "darwin", MemorySize: 8192}
+
+	results, err := f.SetConfigAll([]string{"same", "other-os", "busy", "idle", "missing"}, config)
+	if err != nil {
+		t.Fatalf("SetConfigAll: %v", err)
+	}
+	for _, name := range []string{"same", "other-os", "busy", "idle"} {
+		if results[name] != nil {
+			t.Errorf("%s: %v", name, results[name])
+		}
+	}
+	if results["missing"] == nil {
+		t.Error("missing VM: no error")
+	}
+	stops := f.commandCalls(t, "stop")
+	if len(stops) != 1 || stops[0][1] != "busy" {
+		t.Errorf("stop calls = %v, want only busy", stops)
+	}
+	sets := map[string]bool{}
+	for _, call := range f.commandCalls(t, "set") {
+		sets[call[1]] = true
+	}
+	if !sets["busy"] || !sets["idle"] || sets["same"] || sets["other-os"] {
+		t.Errorf("set calls = %v, want busy and idle", sets)
+	}
+}
+
+func TestDeleteFleet(t *testing.T) {
+	f := newFakeTart(t, `
+case "$1" in
+list)
+	if [ -f "$FAKE_TART_DIR/listed" ]; then
+		# exited stops on its own after the first listing
+		echo '[{"name":"running","state":"running"},{"name":"exited","state":"stopped"}]'
+	else
+		touch "$FAKE_TART_DIR/listed"
+		echo '[{"name":"running","state":"running"},{"name":"exited","state":"running"}]'
+	fi
+	exit 0;;
+esac`)
+	results, err := f.DeleteFleet([]string{"running", "exited", "running", "gone"}, 5)
+	if err != nil {
+		t.Fatalf("DeleteFleet: %v", err)
+	}
+	for name, err := range results {
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	deletes := f.commandCalls(t, "delete")
+	if len(deletes) != 2 {
+		t.Errorf("delete calls = %v, want running and exited once each", deletes)
+	}
+	stops := f.commandCalls(t, "stop")
+	if len(stops) != 1 || stops[0][1] != "running" {
+		t.Errorf("stop calls = %v, want only running", stops)
+	}
+}
+
+func TestPullAll(t *testing.T) {
+	f := newFakeTart(t, `
+case "$1" in
+pull)
+	[ "$2" = ghcr.io/org/broken ] && { echo "manifest unknown" >&2; exit 1; }
+	exit 0;;
+esac`)
+	refs := []string{"ghcr.io/org/a", "ghcr.io/org/broken", "ghcr.io/org/a", "ghcr.io/org/b"}
+	results, err := f.PullAll(refs, 2, false)
+	if err != nil {
+		t.Fatalf("PullAll: %v", err)
+	}
+	tests := []struct {
+		ref     string
+		wantErr bool
+	}{
+		{ref: "ghcr.io/org/a"},
+		{ref: "ghcr.io/org/b"},
+		{ref: "ghcr.io/org/broken", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			err, ok := results[tt.ref]
+			if !ok {
+				t.Fatal("no result")
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("result = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	if len(results) != len(tests) {
+		t.Errorf("got %d results, want %d", len(results), len(tests))
+	}
+	pulled := map[string]int{}
+	for _, call := range f.commandCalls(t, "pull") {
+		pulled[call[1]]++
+	}
+	if pulled["ghcr.io/org/a"] != 1 {
+		t.Errorf("ghcr.io/org/a was pulled %d times, want once", pulled["ghcr.io/org/a"])
+	}
+}
+
+func TestPullAllCancelled(t *testing.T) {
+	f := newFakeTart(t, "")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results, err := f.PullAllContext(ctx, []string{"ghcr.io/org/a", "ghcr.io/org/b"}, 1, false)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("PullAllContext = %v, want context.Canceled", err)
+	}
+	for ref, err := range results {
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("result for %s = %v, want context.Canceled", ref, err)
+		}
+	}
+	if pulls := f.commandCalls(t, "pull"); len(pulls) != 0 {
+		t.Errorf("pulled %q after cancellation", pulls)
+	}
+}
+
+func TestSetConfigAllDuplicateNames(t *testing.T) {
+	f := newFakeTart(t, `
+case "$1" in
+stop)
+	touch "$FAKE_TART_DIR/stopped-$2"
+	exit 0;;
+list)
+	state=running
+	[ -f "$FAKE_TART_DIR/stopped-vm" ] && state=stopped
+	echo '[{"name":"vm","state":"'$state'"}]'
+	exit 0;;
+esac`)
+	f.clk = newFakeClock()
+	f.addVM(t, "vm", `{"os":"darwin","memorySize":4294967296}`)
+	results, err := f.SetConfigAll([]string{"vm", "vm", "vm"}, VMConfig{MemorySize: 8192})
+	if err != nil {
+		t.Fatalf("SetConfigAll: %v", err)
+	}
+	if len(results) != 1 || results["vm"] != nil {
+		t.Errorf("results = %v, want one success", results)
+	}
+	for _, command := range []string{"stop", "set"} {
+		if calls := f.commandCalls(t, command); len(calls) != 1 {
+			t.Errorf("%s calls = %q, want one", command, calls)
+		}
+	}
+}
+
+func TestExportAll(t *testing.T) {
+	f := newFakeTart(t, `
+if [ "$1" = export ]; then
+	echo partial > "$3"
+	[ "$2" = broken ] && { echo "disk busy" >&2; exit 1; }
+	exit 0
+fi`)
+	dir := t.TempDir()
+	specs := []ExportSpec{
+		{Name: "a", Path: filepath.Join(dir, "a.tvm")},
+		{Name: "broken", Path: filepath.Join(dir, "broken.tvm")},
+		{Name: "b", Path: filepath.Join(dir, "b.tvm")},
+	}
+	results, err := f.ExportAll(specs, 2)
+	if err != nil {
+		t.Fatalf("ExportAll: %v", err)
+	}
+	for _, spec := range specs {
+		err, ok := results[spec.Name]
+		if !ok {
+			t.Errorf("no result for %s", spec.Name)
+			continue
+		}
+		wantErr := spec.Name == "broken"
+		if (err != nil) != wantErr {
+			t.Errorf("%s: result = %v, wantErr %v", spec.Name, err, wantErr)
+		}
+		if _, statErr := os.Stat(spec.Path); (statErr == nil) == wantErr {
+			t.Errorf("%s: export file exists = %v, want %v", spec.Name, statErr == nil, !wantErr)
+		}
+	}
+}
+
+func TestExportAllInvalidSpecs(t *testing.T) {
+	tests := []struct {
+		name  string
+		specs []ExportSpec
+	}{
+		{name: "no name", specs: []ExportSpec{{Path: "a.tvm"}}},
+		{name: "no path", specs: []ExportSpec{{Name: "a"}}},
+		{name: "duplicate", specs: []ExportSpec{{Name: "a", Path: "a.tvm"}, {Name: "a", Path: "b.tvm"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeTart(t, "")
+			if _, err := f.ExportAll(tt.specs, 1); err == nil {
+				t.Error("ExportAll succeeded, want an error")
+			}
+			if exports := f.commandCalls(t, "export"); len(exports) != 0 {
+				t.Errorf("export calls = %q, want none", exports)
+			}
+		})
+	}
+}
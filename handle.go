@@ -0,0 +1,346 @@
+package tart
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// readyPattern matches the serial output line Tart prints once a VM is up.
+var readyPattern = regexp.MustCompile(`VM is up`)
+
+// RunHandle represents a VM running in the background.
+// Its serial output is drained for as long as the VM runs, so the guest
+// never stalls on console writes.
+type RunHandle struct {
+	Name string `json:"name"`
+	// Line is the serial output line that signalled readiness.
+	Line string `json:"line"`
+
+	tart    *Tart
+	options RunOptions
+	cmd     *exec.Cmd
+	stderr  bytes.Buffer
+	done    chan struct{}
+	err     error
+
+	mu  sync.Mutex
+	vnc *VNCConnection
+}
+
+// VNC returns the VNC connection details Tart printed for a VM run with the
+// VNC or VNCExperimental options, and whether they have been seen yet.
+func (h *RunHandle) VNC() (VNCConnection, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.vnc == nil {
+		return VNCConnection{}, false
+	}
+	return *h.vnc, true
+}
+
+// observe records details printed by Tart on a line of output.
+func (h *RunHandle) observe(line string) {
+	if !h.options.VNC && !h.options.VNCExperimental {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	conn := VNCConnection{}
+	if h.vnc != nil {
+		conn = *h.vnc
+	}
+	if parseVNCLine(line, &conn) {
+		h.vnc = &conn
+	}
+}
+
+// Done returns a channel that is closed when the VM process exits.
+func (h *RunHandle) Done() <-chan struct{} {
+	return h.done
+}
+
+// Wait waits for the VM process to exit.
+// It returns an error if the process exited with an error.
+func (h *RunHandle) Wait() error {
+	<-h.done
+	if h.err != nil {
+		return fmt.Errorf("VM process exited with error: %w, stderr: %s", h.err, h.stderr.String())
+	}
+	return nil
+}
+
+// Stop interrupts the VM process, which shuts the VM down, and waits for it
+// to exit. With a remote Transport, signalling the local process wouldn't
+// reach the VM, so it's stopped with tart stop on the remote host instead.
+// It returns an error if the process can't be signalled.
+func (h *RunHandle) Stop() error {
+	if h.remote() {
+		return h.stopRemote()
+	}
+	if err := h.cmd.Process.Signal(os.Interrupt); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return fmt.Errorf("failed to stop VM: %w", err)
+	}
+	<-h.done
+	return nil
+}
+
+// Kill forcibly terminates the VM process and waits for it to exit. With a
+// remote Transport, the VM is first stopped without a grace period with tart
+// stop on the remote host.
+// It returns an error if the process can't be killed.
+func (h *RunHandle) Kill() error {
+	if h.remote() {
+		h.tart.run("stop", h.Name, "--timeout", "0")
+	}
+	if err := h.cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return fmt.Errorf("failed to kill VM: %w", err)
+	}
+	<-h.done
+	return nil
+}
+
+// remote reports whether the VM process runs through a remote Transport.
+func (h *RunHandle) remote() bool {
+	return h.tart != nil && h.tart.remote()
+}
+
+// stopRemote stops a VM run through a remote Transport and waits for its
+// process to exit.
+func (h *RunHandle) stopRemote() error {
+	select {
+	case <-h.done:
+		return nil
+	default:
+	}
+	output, err := h.tart.run("stop", h.Name)
+	if err != nil {
+		select {
+		case <-h.done:
+			return nil
+		default:
+		}
+		return fmt.Errorf("failed to stop VM: %w, output: %s", err, string(output))
+	}
+	<-h.done
+	return nil
+}
+
+// track records a VM process started by this instance.
+func (t *Tart) track(h *RunHandle) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.handles == nil {
+		t.handles = map[*RunHandle]struct{}{}
+	}
+	t.handles[h] = struct{}{}
+}
+
+// untrack forgets a VM process once it has exited.
+func (t *Tart) untrack(h *RunHandle) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.handles, h)
+}
+
+// handle returns the handle of a running VM started by this instance.
+func (t *Tart) handle(name string) (*RunHandle, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for h := range t.handles {
+		if h.Name == name {
+			return h, true
+		}
+	}
+	return nil, false
+}
+
+// vmLock serializes starting a VM. It's reference counted so that it can be
+// forgotten once no caller holds or waits for it.
+type vmLock struct {
+	sync.Mutex
+	refs int
+}
+
+// lockVM serializes starting the named VM, returning the function that
+// releases the lock.
+func (t *Tart) lockVM(name string) func() {
+	t.mu.Lock()
+	if t.vmLocks == nil {
+		t.vmLocks = map[string]*vmLock{}
+	}
+	l, ok := t.vmLocks[name]
+	if !ok {
+		l = &vmLock{}
+		t.vmLocks[name] = l
+	}
+	l.refs++
+	t.mu.Unlock()
+	l.Lock()
+	return func() {
+		l.Unlock()
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		l.refs--
+		if l.refs == 0 {
+			delete(t.vmLocks, name)
+		}
+	}
+}
+
+// RunningHandles returns the handles of the VMs this instance started and
+// whose run processes haven't exited yet, sorted by name.
+func (t *Tart) RunningHandles() []*RunHandle {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	handles := make([]*RunHandle, 0, len(t.handles))
+	for h := range t.handles {
+		handles = append(handles, h)
+	}
+	sort.Slice(handles, func(i, j int) bool {
+		return handles[i].Name < handles[j].Name
+	})
+	return handles
+}
+
+// RunDetached runs a VM in the background with the specified options.
+// It returns once the VM is up, with a handle for managing the running VM.
+// It returns an error wrapping ErrVMAlreadyRunning if the VM is already
+// running, an error if it doesn't exist, or if the run process exits before
+// the VM is up.
+func (t *Tart) RunDetached(name string, options RunOptions) (*RunHandle, error) {
+	return t.RunUntilLog(name, readyPattern, 0, options)
+}
+
+// RunUntilLog runs a VM in the background with the specified options.
+// It returns once a line of serial output matches pattern, with a handle for
+// managing the running VM. A timeout of zero waits indefinitely.
+// Concurrent calls for the same VM on one instance are serialized: while one
+// is starting the VM the others wait, then fail with ErrVMAlreadyRunning if
+// it came up.
+// It returns an error if pattern is nil, one wrapping ErrVMAlreadyRunning if
+// the VM is already running, one wrapping ErrVMNotFound if it doesn't exist,
+// an error if the run process exits before a line matches, or if the timeout
+// expires, in which case the VM process is killed. If the VM started but its
+// resource overrides couldn't be restored, both the handle and an error are
+// returned.
+func (t *Tart) RunUntilLog(name string, pattern *regexp.Regexp, timeout time.Duration, options RunOptions) (h *RunHandle, err error) {
+	if pattern == nil {
+		return nil, errors.New("pattern must not be nil")
+	}
+	args, err := runArgs(name, options)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.checkRunFlags(args); err != nil {
+		return nil, err
+	}
+	if err := checkHostSupport(options); err != nil {
+		return nil, err
+	}
+	// Concurrent runs of the same VM through this instance are serialized
+	// until the first is up or has failed, so exactly one of them starts it.
+	unlock := t.lockVM(name)
+	defer unlock()
+	if _, ok := t.handle(name); ok {
+		return nil, fmt.Errorf("%w: %s", ErrVMAlreadyRunning, name)
+	}
+	if err := t.checkRunnable(name); err != nil {
+		return nil, err
+	}
+	if options.MACSeed != "" {
+		if err := t.SetConfig(name, VMConfig{MACAddress: DeterministicMAC(options.MACSeed)}); err != nil {
+			return nil, err
+		}
+	}
+	restore, err := t.overrideResources(name, options)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if restoreErr := restore(); restoreErr != nil && err == nil {
+			err = restoreErr
+		}
+	}()
+
+	cmd := t.command(context.Background(), nil, args...)
+	h = &RunHandle{Name: name, tart: t, options: options, cmd: cmd, done: make(chan struct{})}
+	cmd.Stderr = &h.stderr
+
+	serialOut, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+
+	started := t.clock().Now()
+	if err := t.start(cmd); err != nil {
+		t.debugLog(args, started, nil, nil, err)
+		return nil, fmt.Errorf("failed to start VM: %w", err)
+	}
+	t.track(h)
+	if options.SerialPath != "" {
+		t.recordSerialPath(name, t.resolvePath(options.SerialPath))
+	}
+
+	ready := make(chan string, 1)
+	go func() {
+		// ReadString grows its result past the buffer size as needed, so long
+		// lines are returned whole rather than failing with ErrBufferFull.
+		reader := bufio.NewReader(serialOut)
+		if options.SerialBufferSize > 0 {
+			reader = bufio.NewReaderSize(serialOut, options.SerialBufferSize)
+		}
+		matched := false
+		for {
+			line, err := reader.ReadString('\n')
+			if line != "" {
+				h.observe(line)
+				if options.SerialOutput != nil {
+					options.SerialOutput.Write([]byte(redactVNC(line)))
+				}
+			}
+			if !matched && line != "" && pattern.MatchString(line) {
+				matched = true
+				ready <- strings.TrimRight(line, "\r\n")
+			}
+			if err != nil {
+				break
+			}
+		}
+		h.err = cmd.Wait()
+		t.debugLog(args, started, nil, h.stderr.Bytes(), h.err)
+		t.untrack(h)
+		close(h.done)
+	}()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		expired = t.clock().After(timeout)
+	}
+	select {
+	case h.Line = <-ready:
+		return h, nil
+	case <-h.done:
+		select {
+		case h.Line = <-ready:
+			return h, nil
+		default:
+		}
+		if err := h.Wait(); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("VM process exited before becoming ready")
+	case <-expired:
+		h.Kill()
+		return nil, fmt.Errorf("timed out waiting for VM %s to become ready", name)
+	}
+}
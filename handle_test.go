@@ -0,0 +1,217 @@
+package tart
+
+import (
+	"bytes"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// runningScript is a fake tart script whose run command prints the ready
+// line and stays up until tart stop is called for the VM, or for at most
+// 30 seconds.
+const runningScript = `
+case "$2" in
+--help) exit 0;;
+esac
+case "$1" in
+run)
+	echo "VM is up"
+	i=0
+	while [ ! -f "$FAKE_TART_DIR/stopped-$2" ] && [ $i -lt 600 ]; do sleep 0.05; i=$((i+1)); done
+	exit 0;;
+stop)
+	touch "$FAKE_TART_DIR/stopped-$2"
+	exit 0;;
+esac`
+
+// remoteTransport runs tart locally but, not being a LocalTransport, makes
+// the package treat it as remote.
+type remoteTransport struct {
+	LocalTransport
+}
+
+func TestRunHandleStopRemote(t *testing.T) {
+	f := newFakeTart(t, runningScript)
+	f.Transport = remoteTransport{}
+	f.setList(t, `[{"name":"vm","state":"stopped"}]`)
+	h, err := f.RunDetached("vm", RunOptions{})
+	if err != nil {
+		t.Fatalf("RunDetached: %v", err)
+	}
+	if err := h.Stop(); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if stops := f.commandCalls(t, "stop"); len(stops) != 1 {
+		t.Errorf("stop calls = %v, want the VM stopped with tart stop", stops)
+	}
+}
+
+// chattyScript is like runningScript, but the VM writes heavily to its
+// console once it's up.
+const chattyScript = `
+case "$2" in
+--help) exit 0;;
+esac
+case "$1" in
+run)
+	echo "VM is up"
+	i=0
+	while [ $i -lt 2000 ]; do echo "console line $i padded to make the output larger than any pipe buffer"; i=$((i+1)); done
+	echo "console done"
+	i=0
+	while [ ! -f "$FAKE_TART_DIR/stopped-$2" ] && [ $i -lt 600 ]; do sleep 0.05; i=$((i+1)); done
+	exit 0;;
+stop)
+	touch "$FAKE_TART_DIR/stopped-$2"
+	exit 0;;
+esac`
+
+// syncBuffer is a bytes.Buffer safe for a writer and a reader goroutine.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
+
+func TestRunDrainsOutputAfterBoot(t *testing.T) {
+	f := newFakeTart(t, chattyScript)
+	f.setList(t, `[{"name":"vm","state":"stopped"}]`)
+	var serial syncBuffer
+	if err := f.Run("vm", RunOptions{SerialOutput: &serial}); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	handles := f.RunningHandles()
+	if len(handles) != 1 {
+		t.Fatalf("got %d running handles, want 1", len(handles))
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for !strings.Contains(serial.String(), "console done") {
+		if time.Now().After(deadline) {
+			t.Fatal("console output stopped being drained after boot")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := handles[0].Stop(); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if n := strings.Count(serial.String(), "console line"); n != 2000 {
+		t.Errorf("got %d console lines, want 2000", n)
+	}
+}
+
+func TestRunUntilLogNilPattern(t *testing.T) {
+	f := newFakeTart(t, runningScript)
+	f.setList(t, `[{"name":"vm","state":"stopped"}]`)
+	h, err := f.RunUntilLog("vm", nil, 0, RunOptions{})
+	if err == nil || h != nil {
+		t.Fatalf("RunUntilLog with a nil pattern = %v, %v; want an error", h, err)
+	}
+	if calls := f.commandCalls(t, "run"); len(calls) != 0 {
+		t.Errorf("run was invoked %d times, want none", len(calls))
+	}
+}
+
+func TestRunUntilLogLongLine(t *testing.T) {
+	f := newFakeTart(t, `
+case "$2" in
+--help) exit 0;;
+esac
+case "$1" in
+run)
+	printf 'start '; head -c 10000 /dev/zero | tr '\0' x; echo ' end'
+	i=0
+	while [ ! -f "$FAKE_TART_DIR/stopped" ] && [ $i -lt 600 ]; do sleep 0.05; i=$((i+1)); done
+	exit 0;;
+esac`)
+	f.setList(t, `[{"name":"vm","state":"stopped"}]`)
+	var serial syncBuffer
+	h, err := f.RunUntilLog("vm", regexp.MustCompile(`x end`), 10*time.Second, RunOptions{SerialOutput: &serial, SerialBufferSize: 16})
+	if err != nil {
+		t.Fatalf("RunUntilLog: %v", err)
+	}
+	defer h.Kill()
+	want := "start " + strings.Repeat("x", 10000) + " end"
+	if h.Line != want {
+		t.Errorf("got a ready line of %d bytes, want %d", len(h.Line), len(want))
+	}
+	if serial.String() != want+"\n" {
+		t.Errorf("got %d bytes of serial output, want %d", len(serial.String()), len(want)+1)
+	}
+}
+
+func TestLockVM(t *testing.T) {
+	f := newFakeTart(t, "")
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	holders := map[string]int{}
+	for i := 0; i < 20; i++ {
+		for _, name := range []string{"a", "b"} {
+			wg.Add(1)
+			go func(name string) {
+				defer wg.Done()
+				unlock := f.lockVM(name)
+				mu.Lock()
+				holders[name]++
+				if holders[name] > 1 {
+					t.Errorf("%d callers hold the lock for %s", holders[name], name)
+				}
+				mu.Unlock()
+				time.Sleep(time.Millisecond)
+				mu.Lock()
+				holders[name]--
+				mu.Unlock()
+				unlock()
+			}(name)
+		}
+	}
+	wg.Wait()
+	if n := len(f.vmLocks); n != 0 {
+		t.Errorf("%d VM locks kept after every caller released them", n)
+	}
+}
+
+func TestRunningHandles(t *testing.T) {
+	f := newFakeTart(t, runningScript)
+	f.setList(t, `[{"name":"a","state":"stopped"},{"name":"b","state":"stopped"}]`)
+	handles := map[string]*RunHandle{}
+	for _, name := range []string{"b", "a"} {
+		h, err := f.RunDetached(name, RunOptions{})
+		if err != nil {
+			t.Fatalf("RunDetached %s: %v", name, err)
+		}
+		defer h.Stop()
+		handles[name] = h
+	}
+	names := func() []string {
+		var names []string
+		for _, h := range f.RunningHandles() {
+			names = append(names, h.Name)
+		}
+		return names
+	}
+	if got := names(); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("RunningHandles = %q, want [a b]", got)
+	}
+	if err := handles["a"].Stop(); err != nil {
+		t.Fatal(err)
+	}
+	<-handles["a"].Done()
+	if got := names(); !reflect.DeepEqual(got, []string{"b"}) {
+		t.Errorf("RunningHandles after a exited = %q, want [b]", got)
+	}
+}
@@ -0,0 +1,138 @@
+package tart
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// hostMacOSVersion returns the host's macOS version, e.g. "14.5".
+func hostMacOSVersion() (string, error) {
+	output, err := exec.Command("sw_vers", "-productVersion").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get macOS version: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// appleChipPattern extracts the generation from a CPU brand string such as
+// "Apple M3 Pro".
+var appleChipPattern = regexp.MustCompile(`Apple M(\d+)`)
+
+// hostChipGeneration returns the generation of the host's Apple Silicon chip,
+// e.g. 3 for an M3, or 0 if it can't be determined.
+func hostChipGeneration() int {
+	output, err := exec.Command("sysctl", "-n", "machdep.cpu.brand_string").Output()
+	if err != nil {
+		return 0
+	}
+	m := appleChipPattern.FindStringSubmatch(string(output))
+	if m == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(m[1])
+	return n
+}
+
+// hostArch returns the host's CPU architecture, "arm64" on Apple Silicon
+// even when this process runs under Rosetta.
+func hostArch() string {
+	output, err := exec.Command("sysctl", "-n", "hw.optional.arm64").Output()
+	if err == nil && strings.TrimSpace(string(output)) == "1" {
+		return "arm64"
+	}
+	return runtime.GOARCH
+}
+
+// HostRequirements describes what a caller needs from the host. Zero fields
+// aren't checked.
+type HostRequirements struct {
+	// MinMacOS is the oldest acceptable macOS version, e.g. "14" or "15.1".
+	MinMacOS string `json:"minMacOS"`
+	// Arch is the required CPU architecture, e.g. "arm64".
+	Arch string `json:"arch"`
+	// MinChipGeneration is the oldest acceptable Apple Silicon generation,
+	// e.g. 3 for an M3.
+	MinChipGeneration int `json:"minChipGeneration"`
+}
+
+// CheckHostCompatibility checks the host against the requirements.
+// It returns an error wrapping ErrHostIncompatible that lists everything
+// the host lacks, or an error if the host can't be inspected.
+func CheckHostCompatibility(requirements HostRequirements) error {
+	var missing []string
+	if requirements.MinMacOS != "" {
+		version, err := hostMacOSVersion()
+		if err != nil {
+			return err
+		}
+		if compareVersions(version, requirements.MinMacOS) < 0 {
+			missing = append(missing, fmt.Sprintf("requires macOS %s or newer, host has %s", requirements.MinMacOS, version))
+		}
+	}
+	if requirements.Arch != "" {
+		if arch := hostArch(); arch != requirements.Arch {
+			missing = append(missing, fmt.Sprintf("requires %s, host is %s", requirements.Arch, arch))
+		}
+	}
+	if requirements.MinChipGeneration > 0 {
+		if gen := hostChipGeneration(); gen < requirements.MinChipGeneration {
+			missing = append(missing, fmt.Sprintf("requires an M%d or newer chip", requirements.MinChipGeneration))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrHostIncompatible, strings.Join(missing, "; "))
+	}
+	return nil
+}
+
+// rosettaRuntimePath is the runtime macOS installs with Rosetta 2.
+const rosettaRuntimePath = "/Library/Apple/usr/libexec/oah/libRosettaRuntime"
+
+// RosettaAvailable reports whether Rosetta is installed on the host, which
+// Linux VMs run with the Rosetta option need. Tart can't install it; run
+// "softwareupdate --install-rosetta --agree-to-license" to do so.
+// It returns an error if the installation can't be checked.
+func RosettaAvailable() (bool, error) {
+	_, err := os.Stat(rosettaRuntimePath)
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return false, fmt.Errorf("failed to check for Rosetta: %w", err)
+}
+
+// checkHostSupport returns an error if the host can't provide a feature the
+// run options ask for.
+func checkHostSupport(options RunOptions) error {
+	if options.Nested {
+		version, err := hostMacOSVersion()
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrNestedUnsupported, err)
+		}
+		if compareVersions(version, "15") < 0 {
+			return fmt.Errorf("%w: requires macOS 15 or newer, host has %s", ErrNestedUnsupported, version)
+		}
+		if gen := hostChipGeneration(); gen < 3 {
+			return fmt.Errorf("%w: requires an M3 or newer chip", ErrNestedUnsupported)
+		}
+	}
+	if options.Rosetta != "" {
+		ok, err := RosettaAvailable()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("%w: install it with \"softwareupdate --install-rosetta --agree-to-license\"", ErrRosettaUnavailable)
+		}
+	}
+	return nil
+}
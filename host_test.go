@@ -0,0 +1,73 @@
+package tart
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeHost installs sw_vers and sysctl commands on PATH that describe a host
+// running the given macOS version on the given Apple chip.
+func fakeHost(t *testing.T, version string, chip string) {
+	t.Helper()
+	dir := t.TempDir()
+	scripts := map[string]string{
+		"sw_vers": "#!/bin/sh\necho " + version + "\n",
+		"sysctl": `#!/bin/sh
+case "$2" in
+machdep.cpu.brand_string) echo "` + chip + `";;
+hw.optional.arm64) echo 1;;
+esac
+`,
+	}
+	for name, script := range scripts {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestCheckHostCompatibility(t *testing.T) {
+	tests := []struct {
+		name         string
+		requirements HostRequirements
+		wantErr      bool
+		wantMissing  []string
+	}{
+		{name: "no requirements"},
+		{name: "satisfied", requirements: HostRequirements{MinMacOS: "14", Arch: "arm64", MinChipGeneration: 2}},
+		{name: "same version", requirements: HostRequirements{MinMacOS: "14.5"}},
+		{
+			name:         "too old",
+			requirements: HostRequirements{MinMacOS: "15.1"},
+			wantErr:      true,
+			wantMissing:  []string{"requires macOS 15.1 or newer, host has 14.5"},
+		},
+		{
+			name:         "everything missing",
+			requirements: HostRequirements{MinMacOS: "15", Arch: "amd64", MinChipGeneration: 3},
+			wantErr:      true,
+			wantMissing:  []string{"macOS 15", "requires amd64, host is arm64", "requires an M3 or newer chip"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeHost(t, "14.5", "Apple M2 Pro")
+			err := CheckHostCompatibility(tt.requirements)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckHostCompatibility = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, ErrHostIncompatible) {
+				t.Errorf("CheckHostCompatibility = %v, want ErrHostIncompatible", err)
+			}
+			for _, want := range tt.wantMissing {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("CheckHostCompatibility = %v, want it to mention %q", err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package tart
+
+// Hypervisor is the set of stable, commonly-used operations on VMs. *Tart
+// implements it; code that depends on Hypervisor rather than *Tart can be
+// tested against the in-memory implementation in the fake package.
+type Hypervisor interface {
+	Create(name string, options CreateOptions) error
+	Clone(sourceName string, newName string, options CloneOptions) error
+	Delete(name string) error
+	Rename(oldName string, newName string) (VMState, error)
+	List(config ListOptions) ([]VMState, error)
+	State(name string) (VMState, error)
+	Exists(name string) (bool, error)
+	Run(name string, options RunOptions) error
+	Stop(name string, timeout int) error
+	Suspend(name string) error
+	IP(name string, wait int, resolver string) (string, error)
+	Config(name string) (VMConfig, error)
+	SetConfig(name string, config VMConfig) error
+	PullWithOptions(name string, options PullOptions) error
+	Push(name string, options PushOptions) error
+}
+
+var _ Hypervisor = (*Tart)(nil)
@@ -0,0 +1,83 @@
+package tart
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SameImage reports whether two VMs, local or in the OCI cache, hold the
+// same image. Equality is determined by digest: images in the OCI cache are
+// compared by the manifest digest their tags resolve to, and otherwise the
+// SHA-256 digests of the VMs' disk images are compared. A clone therefore
+// only matches its source until either of them is booted or modified.
+// It returns an error wrapping ErrVMNotFound if either VM doesn't exist, or
+// an error if the disk images can't be read.
+func (t *Tart) SameImage(a string, b string) (bool, error) {
+	pathA, err := t.Path(a)
+	if err != nil {
+		return false, err
+	}
+	pathB, err := t.Path(b)
+	if err != nil {
+		return false, err
+	}
+	digestA, okA := ociDigest(pathA)
+	digestB, okB := ociDigest(pathB)
+	if okA && okB {
+		return digestA == digestB, nil
+	}
+	diskA, diskB := filepath.Join(pathA, "disk.img"), filepath.Join(pathB, "disk.img")
+	infoA, err := os.Stat(diskA)
+	if err != nil {
+		return false, fmt.Errorf("failed to read disk image of %s: %w", a, err)
+	}
+	infoB, err := os.Stat(diskB)
+	if err != nil {
+		return false, fmt.Errorf("failed to read disk image of %s: %w", b, err)
+	}
+	if infoA.Size() != infoB.Size() {
+		return false, nil
+	}
+	if os.SameFile(infoA, infoB) {
+		return true, nil
+	}
+	if digestA, err = fileDigest(diskA); err != nil {
+		return false, fmt.Errorf("failed to hash disk image of %s: %w", a, err)
+	}
+	if digestB, err = fileDigest(diskB); err != nil {
+		return false, fmt.Errorf("failed to hash disk image of %s: %w", b, err)
+	}
+	return digestA == digestB, nil
+}
+
+// ociDigest returns the manifest digest of an OCI cache entry, whose tags
+// are symlinks to a directory named after the digest.
+func ociDigest(path string) (string, bool) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", false
+	}
+	base := filepath.Base(resolved)
+	if !strings.HasPrefix(base, "sha256:") {
+		return "", false
+	}
+	return base, true
+}
+
+// fileDigest returns the hex-encoded SHA-256 digest of a file.
+func fileDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("sha256:%x", h.Sum(nil)), nil
+}
@@ -0,0 +1,65 @@
+package tart
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSameImage(t *testing.T) {
+	f := newFakeTart(t, "")
+	disk := func(name string, data string) {
+		t.Helper()
+		f.addVM(t, name, `{}`)
+		if err := os.WriteFile(filepath.Join(f.vmDir(name), "disk.img"), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	disk("base", "golden image")
+	disk("clone", "golden image")
+	disk("booted", "golden imagf")
+	disk("grown", "golden image, grown")
+	f.addVM(t, "diskless", `{}`)
+
+	oci := filepath.Join(f.ConfigDir, "cache", "OCIs", "ghcr.io", "org", "img")
+	for _, digest := range []string{"sha256:aaa", "sha256:bbb"} {
+		if err := os.MkdirAll(filepath.Join(oci, digest), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for tag, digest := range map[string]string{"latest": "sha256:aaa", "stable": "sha256:aaa", "next": "sha256:bbb"} {
+		if err := os.Symlink(digest, filepath.Join(oci, tag)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		a, b    string
+		want    bool
+		wantErr error
+	}{
+		{a: "base", b: "clone", want: true},
+		{a: "base", b: "base", want: true},
+		{a: "base", b: "booted"},
+		{a: "base", b: "grown"},
+		{a: "ghcr.io/org/img:latest", b: "ghcr.io/org/img:stable", want: true},
+		{a: "ghcr.io/org/img:latest", b: "ghcr.io/org/img@sha256:aaa", want: true},
+		{a: "ghcr.io/org/img:latest", b: "ghcr.io/org/img:next"},
+		{a: "base", b: "missing", wantErr: ErrVMNotFound},
+		{a: "missing", b: "base", wantErr: ErrVMNotFound},
+	}
+	for _, tt := range tests {
+		got, err := f.SameImage(tt.a, tt.b)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("SameImage(%s, %s) error = %v, want %v", tt.a, tt.b, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("SameImage(%s, %s) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+	if _, err := f.SameImage("base", "diskless"); err == nil {
+		t.Error("SameImage with a VM without a disk succeeded")
+	}
+}
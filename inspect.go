@@ -0,0 +1,127 @@
+package tart
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// inspectTimeout bounds the registry queries made by InspectRemote.
+const inspectTimeout = 30 * time.Second
+
+// uncompressedSizeAnnotation is the annotation Tart puts on disk layers to
+// record their uncompressed size.
+const uncompressedSizeAnnotation = "org.cirruslabs.tart.uncompressed-size"
+
+// RemoteImageInfo describes a remote image without pulling it.
+type RemoteImageInfo struct {
+	Reference string `json:"reference"`
+	Digest    string `json:"digest"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	// Size is the total compressed size of the image's layers, which is
+	// roughly what a pull downloads.
+	Size int64 `json:"size"`
+	// UncompressedSize is the disk space the image takes once pulled, or
+	// zero if the image doesn't record it.
+	UncompressedSize int64 `json:"uncompressedSize"`
+}
+
+// InspectOptions represents the options for inspecting a remote image.
+type InspectOptions struct {
+	Insecure bool `json:"insecure"`
+	// Credentials, if set, authenticate the registry queries.
+	Credentials *Credentials `json:"-"`
+}
+
+// ociManifest is the subset of an OCI image manifest InspectRemote uses.
+type ociManifest struct {
+	Config struct {
+		Digest string `json:"digest"`
+	} `json:"config"`
+	Layers []struct {
+		Size        int64             `json:"size"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"layers"`
+}
+
+// InspectRemote queries the registry for a remote image's OS, architecture
+// and size without pulling it. A reference without a registry host, such as
+// "cirruslabs/macos-sonoma-base", uses the instance's Host.
+// It returns an error if the reference is invalid or the registry can't be
+// queried. Use InspectRemoteWithOptions for private registries.
+func (t *Tart) InspectRemote(ref string) (RemoteImageInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), inspectTimeout)
+	defer cancel()
+	return t.InspectRemoteWithOptions(ctx, ref, InspectOptions{})
+}
+
+// InspectRemoteWithOptions is like InspectRemote with the specified options,
+// bounded by the context instead of a default timeout.
+// It returns an error if the reference is invalid or the registry can't be
+// queried.
+func (t *Tart) InspectRemoteWithOptions(ctx context.Context, ref string, options InspectOptions) (RemoteImageInfo, error) {
+	info := RemoteImageInfo{Reference: ref}
+	r, err := parseReference(t.qualifyReference(ref))
+	if err != nil {
+		return info, err
+	}
+	c := &registryClient{http: http.DefaultClient, insecure: options.Insecure, credentials: options.Credentials}
+
+	resp, err := c.do(ctx, http.MethodGet, c.url(r, "manifests/"+r.Reference), manifestMediaTypes)
+	if err != nil {
+		return info, fmt.Errorf("failed to fetch manifest: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return info, fmt.Errorf("failed to fetch manifest: %s", resp.Status)
+	}
+	var manifest ociManifest
+	if err := json.NewDecoder(resp.Body).Decode(&manifest); err != nil {
+		return info, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	info.Digest = resp.Header.Get("Docker-Content-Digest")
+	for _, layer := range manifest.Layers {
+		info.Size += layer.Size
+		if size, err := strconv.ParseInt(layer.Annotations[uncompressedSizeAnnotation], 10, 64); err == nil {
+			info.UncompressedSize += size
+		}
+	}
+
+	if manifest.Config.Digest != "" {
+		resp, err := c.do(ctx, http.MethodGet, c.url(r, "blobs/"+manifest.Config.Digest), nil)
+		if err != nil {
+			return info, fmt.Errorf("failed to fetch image config: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return info, fmt.Errorf("failed to fetch image config: %s", resp.Status)
+		}
+		var config struct {
+			OS           string `json:"os"`
+			Architecture string `json:"architecture"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&config); err != nil {
+			return info, fmt.Errorf("failed to parse image config: %w", err)
+		}
+		info.OS, info.Arch = config.OS, config.Architecture
+	}
+	return info, nil
+}
+
+// qualifyReference prefixes a reference with the instance's Host if its
+// first component doesn't look like a registry host.
+func (t *Tart) qualifyReference(ref string) string {
+	if t.Host == "" {
+		return ref
+	}
+	first, _, found := strings.Cut(ref, "/")
+	if found && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		return ref
+	}
+	return t.Host + "/" + ref
+}
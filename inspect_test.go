@@ -0,0 +1,65 @@
+package tart
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestInspectRemote(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/org/image/manifests/latest":
+			w.Header().Set("Docker-Content-Digest", "sha256:abc")
+			w.Write([]byte(`{
+	"config": {"digest": "sha256:cfg"},
+	"layers": [
+		{"size": 100},
+		{"size": 250, "annotations": {"org.cirruslabs.tart.uncompressed-size": "1000"}}
+	]
+}`))
+		case "/v2/org/image/blobs/sha256:cfg":
+			w.Write([]byte(`{"os":"darwin","architecture":"arm64"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	tart := &Tart{Host: strings.TrimPrefix(srv.URL, "http://")}
+
+	info, err := tart.InspectRemoteWithOptions(context.Background(), "org/image:latest", InspectOptions{Insecure: true})
+	if err != nil {
+		t.Fatalf("InspectRemoteWithOptions: %v", err)
+	}
+	want := RemoteImageInfo{Reference: "org/image:latest", Digest: "sha256:abc", OS: "darwin", Arch: "arm64", Size: 350, UncompressedSize: 1000}
+	if info != want {
+		t.Errorf("info = %+v, want %+v", info, want)
+	}
+	if _, err := tart.InspectRemoteWithOptions(context.Background(), "org/missing:latest", InspectOptions{Insecure: true}); err == nil {
+		t.Error("inspecting a missing image succeeded")
+	}
+}
+
+func TestQualifyReference(t *testing.T) {
+	tests := []struct {
+		host string
+		ref  string
+		want string
+	}{
+		{host: "", ref: "org/image", want: "org/image"},
+		{host: "ghcr.io", ref: "org/image", want: "ghcr.io/org/image"},
+		{host: "ghcr.io", ref: "registry.example.com/org/image", want: "registry.example.com/org/image"},
+		{host: "ghcr.io", ref: "localhost:5000/image", want: "localhost:5000/image"},
+		{host: "ghcr.io", ref: "localhost/image", want: "localhost/image"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			tart := &Tart{Host: tt.host}
+			if got := tart.qualifyReference(tt.ref); got != tt.want {
+				t.Errorf("qualifyReference(%q) = %q, want %q", tt.ref, got, tt.want)
+			}
+		})
+	}
+}
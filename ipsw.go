@@ -0,0 +1,71 @@
+package tart
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// zipMagic is the signature every zip archive, and thus every IPSW, starts with.
+var zipMagic = []byte("PK\x03\x04")
+
+// VerifyIPSW checks that the file at path looks like a complete IPSW: a zip
+// archive with a readable central directory containing a BuildManifest.plist.
+// It returns an error describing the problem if the file is missing,
+// truncated or not an IPSW.
+func VerifyIPSW(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open IPSW: %w", err)
+	}
+	defer f.Close()
+
+	magic := make([]byte, len(zipMagic))
+	if _, err := io.ReadFull(f, magic); err != nil || !bytes.Equal(magic, zipMagic) {
+		return fmt.Errorf("invalid IPSW %s: not a zip archive", path)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat IPSW: %w", err)
+	}
+	r, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		return fmt.Errorf("invalid IPSW %s: unreadable zip central directory, the file may be truncated: %w", path, err)
+	}
+	for _, file := range r.File {
+		if strings.EqualFold(file.Name, "BuildManifest.plist") {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid IPSW %s: missing BuildManifest.plist", path)
+}
+
+// VerifyIPSWChecksum checks the file at path like VerifyIPSW and also that
+// its SHA-256 digest matches checksum, given in hex.
+// It returns an error if the file is invalid or the digests don't match.
+func VerifyIPSWChecksum(path string, checksum string) error {
+	if err := VerifyIPSW(path); err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open IPSW: %w", err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("failed to hash IPSW: %w", err)
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	want := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(checksum)), "sha256:")
+	if sum != want {
+		return fmt.Errorf("invalid IPSW %s: checksum mismatch, expected %s, got %s", path, want, sum)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package tart
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// zipWith returns a zip archive containing empty files with the given names.
+func zipWith(t *testing.T, names ...string) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	w := zip.NewWriter(&b)
+	for _, name := range names {
+		if _, err := w.Create(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestVerifyIPSW(t *testing.T) {
+	ipsw := zipWith(t, "BuildManifest.plist", "Restore.plist")
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr string
+	}{
+		{name: "valid", data: ipsw},
+		{name: "not a zip", data: []byte("<html>404</html>"), wantErr: "not a zip archive"},
+		{name: "truncated", data: ipsw[:len(ipsw)-30], wantErr: "may be truncated"},
+		{name: "no manifest", data: zipWith(t, "Restore.plist"), wantErr: "missing BuildManifest.plist"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "restore.ipsw")
+			if err := os.WriteFile(path, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			err := VerifyIPSW(path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("VerifyIPSW = %v, want nil", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("VerifyIPSW = %v, want an error mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+	if err := VerifyIPSW(filepath.Join(t.TempDir(), "missing.ipsw")); err == nil {
+		t.Error("VerifyIPSW of a missing file succeeded")
+	}
+}
+
+func TestVerifyIPSWChecksum(t *testing.T) {
+	data := zipWith(t, "BuildManifest.plist")
+	path := filepath.Join(t.TempDir(), "restore.ipsw")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+	hexSum := hex.EncodeToString(sum[:])
+	tests := []struct {
+		checksum string
+		wantErr  bool
+	}{
+		{checksum: hexSum},
+		{checksum: "sha256:" + strings.ToUpper(hexSum)},
+		{checksum: strings.Repeat("0", 64), wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := VerifyIPSWChecksum(path, tt.checksum); (err != nil) != tt.wantErr {
+			t.Errorf("VerifyIPSWChecksum(%q) = %v, wantErr %v", tt.checksum, err, tt.wantErr)
+		}
+	}
+}
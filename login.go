@@ -0,0 +1,107 @@
+package tart
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// keychainLabel is the label Tart gives the registry credentials it stores
+// in the login keychain.
+const keychainLabel = "Tart Credentials"
+
+// keychainNotFound is the exit status of the security tool when no item
+// matches.
+const keychainNotFound = 44
+
+// keychainAccountPattern extracts the account name from the attributes the
+// security tool prints for a keychain item.
+var keychainAccountPattern = regexp.MustCompile(`"acct"<blob>="([^"]*)"`)
+
+// LoginStatus reports whether credentials for the registry in Host are
+// stored, and for which user, so callers can skip redundant logins or ask
+// for authentication up front. It looks where Tart does: the login keychain
+// entry written by Login, then the auths in ~/.docker/config.json. Stored
+// credentials aren't checked against the registry, and credentials from
+// Docker credential helpers or the TART_REGISTRY_USERNAME and
+// TART_REGISTRY_PASSWORD environment variables aren't reported. The
+// credential stores of this machine are inspected even with a remote
+// Transport.
+// It returns false without error if no credentials are stored, and an error
+// if Host is empty or a credential store can't be read.
+func (t *Tart) LoginStatus() (loggedIn bool, username string, err error) {
+	if t.Host == "" {
+		return false, "", errors.New("no registry host configured")
+	}
+	loggedIn, username, err = keychainLogin(t.Host)
+	if err != nil || loggedIn {
+		return loggedIn, username, err
+	}
+	return dockerConfigLogin(t.Host)
+}
+
+// keychainLogin looks up the credentials Tart stored for host in the login
+// keychain. Hosts without the security tool have no keychain.
+func keychainLogin(host string) (bool, string, error) {
+	path, err := exec.LookPath("security")
+	if err != nil {
+		return false, "", nil
+	}
+	output, err := exec.Command(path, "find-internet-password", "-s", host, "-l", keychainLabel).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == keychainNotFound {
+			return false, "", nil
+		}
+		return false, "", fmt.Errorf("failed to read keychain: %w", err)
+	}
+	var username string
+	if m := keychainAccountPattern.FindSubmatch(output); m != nil {
+		username = string(m[1])
+	}
+	return true, username, nil
+}
+
+// dockerConfigLogin looks up inline credentials for host in the Docker
+// client configuration.
+func dockerConfigLogin(host string) (bool, string, error) {
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return false, "", nil
+		}
+		dir = filepath.Join(home, ".docker")
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "config.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, "", nil
+	}
+	if err != nil {
+		return false, "", fmt.Errorf("failed to read Docker config: %w", err)
+	}
+	var config struct {
+		Auths map[string]struct {
+			Auth string `json:"auth"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return false, "", fmt.Errorf("failed to parse Docker config: %w", err)
+	}
+	entry, ok := config.Auths[host]
+	if !ok || entry.Auth == "" {
+		return false, "", nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+	if err != nil {
+		return false, "", fmt.Errorf("failed to parse Docker config: invalid auth for %s: %w", host, err)
+	}
+	username, _, _ := strings.Cut(string(decoded), ":")
+	return true, username, nil
+}
@@ -0,0 +1,69 @@
+package tart
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoginStatus(t *testing.T) {
+	tests := []struct {
+		name         string
+		keychain     string
+		dockerConfig string
+		wantLoggedIn bool
+		wantUser     string
+		wantErr      bool
+	}{
+		{name: "nothing stored", keychain: "exit 44"},
+		{
+			name:         "keychain",
+			keychain:     `echo 'keychain: "login.keychain-db"'; echo '    "acct"<blob>="bot"'`,
+			dockerConfig: `{"auths":{"ghcr.io":{"auth":"ZG9jazpwdw=="}}}`,
+			wantLoggedIn: true,
+			wantUser:     "bot",
+		},
+		{
+			name:         "docker config",
+			keychain:     "exit 44",
+			dockerConfig: `{"auths":{"ghcr.io":{"auth":"ZG9jazpwdw=="}}}`,
+			wantLoggedIn: true,
+			wantUser:     "dock",
+		},
+		{name: "docker config for another host", keychain: "exit 44", dockerConfig: `{"auths":{"quay.io":{"auth":"ZG9jazpwdw=="}}}`},
+		{name: "unreadable keychain", keychain: "exit 1", wantErr: true},
+		{name: "invalid docker config", keychain: "exit 44", dockerConfig: `{"auths":`, wantErr: true},
+		{name: "invalid docker auth", keychain: "exit 44", dockerConfig: `{"auths":{"ghcr.io":{"auth":"%%"}}}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeTart(t, "")
+			f.Host = "ghcr.io"
+			security := "#!/bin/sh\n" + tt.keychain + "\n"
+			if err := os.WriteFile(filepath.Join(f.dir, "security"), []byte(security), 0755); err != nil {
+				t.Fatal(err)
+			}
+			dockerDir := t.TempDir()
+			t.Setenv("DOCKER_CONFIG", dockerDir)
+			if tt.dockerConfig != "" {
+				if err := os.WriteFile(filepath.Join(dockerDir, "config.json"), []byte(tt.dockerConfig), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			loggedIn, user, err := f.LoginStatus()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoginStatus error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if loggedIn != tt.wantLoggedIn || user != tt.wantUser {
+				t.Errorf("LoginStatus = %v, %q, want %v, %q", loggedIn, user, tt.wantLoggedIn, tt.wantUser)
+			}
+		})
+	}
+}
+
+func TestLoginStatusWithoutHost(t *testing.T) {
+	f := newFakeTart(t, "")
+	if _, _, err := f.LoginStatus(); err == nil {
+		t.Error("LoginStatus succeeded without a Host")
+	}
+}
@@ -3,92 +3,311 @@
 package tart
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sync"
+	"time"
 )
 
 // Tart represents the Tart hypervisor.
 type Tart struct {
 	ConfigDir string `json:"configDir"`
 	Host      string `json:"host"`
+	// DefaultUsers overrides the conventional SSH username per guest OS
+	// (e.g. "darwin", "linux"). See DefaultUser.
+	DefaultUsers map[string]string `json:"defaultUsers"`
+	// PollBackoff controls the delay between polls in methods that wait
+	// for a condition, such as WaitForState. The zero value uses
+	// DefaultBackoff.
+	PollBackoff Backoff `json:"pollBackoff"`
+	// Nice is the scheduling priority applied to spawned tart processes,
+	// from -20 (highest) to 19 (lowest). Zero leaves the priority unchanged.
+	// It's applied right after the process starts, and only affects CPU
+	// scheduling; macOS offers no portable way to lower IO priority.
+	Nice int `json:"nice"`
+	// PrepareCmd, if set, is called with every tart command after its
+	// arguments, environment and stdio are set up, right before it's started. It's an
+	// escape hatch for process control this package doesn't expose; changing
+	// the arguments, stdio or environment may break the calling method.
+	PrepareCmd func(cmd *exec.Cmd) `json:"-"`
+	// WorkDir is the working directory for tart commands. Relative paths in
+	// options, such as IPSW, export and dir mount paths, are resolved
+	// against it, including by this package's own checks. Empty uses the
+	// current process's working directory.
+	WorkDir string `json:"workDir"`
+	// SkipPrecheck disables the List call Create, Clone and Import make to
+	// check that the target name is free. That check is slow and racy when
+	// several callers create VMs at once; without it Tart's own uniqueness
+	// check applies and its error is still reported as ErrVMAlreadyExists,
+	// at the cost of Tart possibly doing some work before failing.
+	SkipPrecheck bool `json:"skipPrecheck"`
+	// DefaultTimeout bounds every tart command that runs to completion
+	// (everything except running a VM) when the caller's context has no
+	// deadline of its own. A context with a deadline always takes
+	// precedence. Zero means no default timeout.
+	DefaultTimeout time.Duration `json:"defaultTimeout"`
+	// DefaultConcurrency and DefaultChunkSize are used for registry
+	// transfers (push, pull and clone; chunk size applies to push only)
+	// whose options leave the corresponding value at zero.
+	DefaultConcurrency int `json:"defaultConcurrency"`
+	DefaultChunkSize   int `json:"defaultChunkSize"`
+	// FileMode is the permission mode of the files this package writes:
+	// exports, spec files, metadata sidecars, recorded digests, copied
+	// suspend state and files copied into shared directories. It's applied
+	// regardless of the process's umask. Zero means 0600.
+	FileMode os.FileMode `json:"fileMode"`
+	// DebugLogPath, if set, is a file every tart command is appended to once
+	// it finishes, with its start time, arguments, exit status, stdout and
+	// stderr, for troubleshooting. A running VM's serial output isn't
+	// included. VNC and registry passwords are redacted. Each record also
+	// notes the EffectiveConfigDir.
+	DebugLogPath string `json:"debugLogPath"`
+	// Transport builds the processes that run tart commands, for example an
+	// SSHTransport to control Tart on a remote Mac, in which case ConfigDir
+	// is a path on the remote host. Nil runs tart locally.
+	Transport Transport `json:"-"`
+	// Metadata stores the metadata attached to VMs. Nil uses a
+	// FileMetadataStore in the config directory.
+	Metadata MetadataStore `json:"-"`
+
+	mu      sync.Mutex
+	closers []func() error
+	handles map[*RunHandle]struct{}
+	clk     clock
+	serial  map[string]string
+
+	flags   map[string]map[string]bool
+	logMu   sync.Mutex
+	vmLocks map[string]*vmLock
+	pulls   map[string][]*pullOp
+	// configDirOK records that ConfigDir passed checkConfigDir.
+	configDirOK bool
 }
 
-// New creates a new Tart instance with a custom config directory.
-// It returns an error if the 'tart' command is not found in the system PATt.
+// New creates a new Tart instance using the default config directory.
+// It returns an error if the 'tart' command is not found in the system PATH,
+// or a *ConfigDirError if the config directory isn't writable.
 func New() (*Tart, error) {
+	return NewWithConfigDir(getConfigDir())
+}
+
+// NewWithConfigDir creates a new Tart instance with a custom config directory.
+// It returns an error if the 'tart' command is not found in the system PATH,
+// or a *ConfigDirError if the config directory doesn't exist or isn't writable.
+func NewWithConfigDir(configDir string) (*Tart, error) {
 	// Validate that TART is on the path
 	_, err := exec.LookPath("tart")
 	if err != nil {
 		return nil, errors.New("tart command not found in PATH")
 	}
-	// Configure the config directory
-	configDir := getConfigDir()
+	// Validate the config directory
+	if err := checkWritable(configDir); err != nil {
+		return nil, err
+	}
 	return &Tart{
 		ConfigDir: configDir,
 	}, nil
 }
 
-// setTartHome sets the TART_HOME environment variable for the given command.
-// It returns an error if the specified config directory does not exist.
-func (t *Tart) setTartHome(cmd *exec.Cmd) error {
+// onClose registers a function that releases a resource when Close is called.
+func (t *Tart) onClose(fn func() error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closers = append(t.closers, fn)
+}
+
+// Close releases the resources held by the Tart instance, such as signal
+// handlers and background goroutines, in the reverse order they were
+// acquired. It's safe to call multiple times. VMs started with RunDetached
+// keep running; stop their RunHandles separately.
+// It returns an error listing the resources that couldn't be released.
+func (t *Tart) Close() error {
+	t.mu.Lock()
+	closers := t.closers
+	t.closers = nil
+	t.mu.Unlock()
+	var errs []error
+	for i := len(closers) - 1; i >= 0; i-- {
+		if err := closers[i](); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// transport returns the transport in use.
+func (t *Tart) transport() Transport {
+	if t.Transport != nil {
+		return t.Transport
+	}
+	return LocalTransport{}
+}
+
+// command builds a Tart command bound to the context, with TART_HOME set to
+// the config directory and any additional environment variables.
+func (t *Tart) command(ctx context.Context, env []string, args ...string) *exec.Cmd {
 	if t.ConfigDir != "" {
-		if _, err := os.Stat(t.ConfigDir); os.IsNotExist(err) {
-			return errors.New("config directory does not exist")
+		env = append([]string{"TART_HOME=" + t.ConfigDir}, env...)
+	}
+	cmd := t.transport().Command(ctx, env, args...)
+	cmd.Dir = t.WorkDir
+	return cmd
+}
+
+// checkConfigDir returns a *ConfigDirError if ConfigDir doesn't exist or
+// isn't writable, so an instance built as a struct literal rather than with
+// NewWithConfigDir still reports a bad directory. A directory that passes is
+// not checked again. With a remote Transport, ConfigDir is a path on the
+// remote host and can't be checked here.
+func (t *Tart) checkConfigDir() error {
+	if t.ConfigDir == "" || t.remote() {
+		return nil
+	}
+	t.mu.Lock()
+	ok := t.configDirOK
+	t.mu.Unlock()
+	if ok {
+		return nil
+	}
+	if err := checkWritable(t.ConfigDir); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	t.configDirOK = true
+	t.mu.Unlock()
+	return nil
+}
+
+// start starts a Tart command, running the PrepareCmd hook beforehand and
+// applying the configured priority afterwards.
+// It returns a *ConfigDirError if the config directory is unusable.
+func (t *Tart) start(cmd *exec.Cmd) error {
+	if err := t.checkConfigDir(); err != nil {
+		return err
+	}
+	if t.PrepareCmd != nil {
+		t.PrepareCmd(cmd)
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	if t.Nice != 0 {
+		if err := setPriority(cmd.Process.Pid, t.Nice); err != nil {
+			cmd.Process.Kill()
+			cmd.Wait()
+			return fmt.Errorf("failed to set process priority: %w", err)
 		}
-		cmd.Env = append(os.Environ(), fmt.Sprintf("TART_HOME=%s", t.ConfigDir))
 	}
 	return nil
 }
 
 // run is a helper function to execute Tart commands
 func (t *Tart) run(args ...string) ([]byte, error) {
-	cmd := exec.Command("tart", args...)
-	t.setTartHome(cmd)
+	return t.runContext(context.Background(), args...)
+}
 
-	// Create pipes for stdout and stderr
-	stdoutPipe, err := cmd.StdoutPipe()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+// runContext executes a Tart command, killing it if the context is done
+// before it completes.
+func (t *Tart) runContext(ctx context.Context, args ...string) ([]byte, error) {
+	return t.runEnv(ctx, nil, args...)
+}
+
+// runEnv executes a Tart command with additional environment variables.
+func (t *Tart) runEnv(ctx context.Context, env []string, args ...string) ([]byte, error) {
+	return t.runStream(ctx, env, nil, args...)
+}
+
+// runStream is like runEnv, but also copies the command's stdout to w as it's
+// produced, if w is not nil.
+func (t *Tart) runStream(ctx context.Context, env []string, w io.Writer, args ...string) ([]byte, error) {
+	if _, ok := ctx.Deadline(); !ok && t.DefaultTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.DefaultTimeout)
+		defer cancel()
 	}
-	stderrPipe, err := cmd.StderrPipe()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create stderr pipe: %w", err)
+	cmd := t.command(ctx, env, args...)
+
+	// Collect stdout and stderr concurrently so that a command writing heavily
+	// to one stream can't block on a full pipe while we wait on the other.
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	if w != nil {
+		cmd.Stdout = io.MultiWriter(&stdout, w)
 	}
+	cmd.Stderr = &stderr
 
 	// Start the command
-	if err := cmd.Start(); err != nil {
+	started := t.clock().Now()
+	if err := t.start(cmd); err != nil {
+		t.debugLog(args, started, nil, nil, err)
 		return nil, fmt.Errorf("failed to start command: %w", err)
 	}
 
-	// Read stdout and stderr
-	stdout, err := io.ReadAll(stdoutPipe)
+	// Wait for the command to finish
+	err := cmd.Wait()
+	t.debugLog(args, started, stdout.Bytes(), stderr.Bytes(), err)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read stdout: %w", err)
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("command canceled: %w, stderr: %s", ctx.Err(), stderr.String())
+		}
+		return nil, &CommandError{Args: args, Stderr: stderr.String(), Err: err, Command: t.shellCommand(env, args)}
 	}
-	stderr, err := io.ReadAll(stderrPipe)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read stderr: %w", err)
+
+	return stdout.Bytes(), nil
+}
+
+// resolvePath resolves a relative path against WorkDir.
+func (t *Tart) resolvePath(path string) string {
+	if t.WorkDir == "" || filepath.IsAbs(path) {
+		return path
 	}
+	return filepath.Join(t.WorkDir, path)
+}
 
-	// Wait for the command to finish
-	if err := cmd.Wait(); err != nil {
-		return nil, fmt.Errorf("command failed: %w, stderr: %s", err, stderr)
+// EffectiveConfigDir returns the config directory tart commands use, which
+// helps diagnose VMs missing because another store is in effect: ConfigDir
+// if set, otherwise the TART_HOME environment variable Tart inherits from
+// this process, otherwise ~/.tart. It only resolves the path: nothing is
+// created, and it's empty if there's no home directory to resolve ~/.tart
+// against. With a remote Transport and no ConfigDir the result describes
+// this machine, not the remote host.
+func (t *Tart) EffectiveConfigDir() string {
+	if t.ConfigDir != "" {
+		return t.ConfigDir
+	}
+	if home := os.Getenv("TART_HOME"); home != "" {
+		return home
 	}
+	dir, _ := defaultConfigDir()
+	return dir
+}
 
-	return stdout, nil
+// vmDir returns the directory holding a local VM's bundle.
+func (t *Tart) vmDir(name string) string {
+	return filepath.Join(t.EffectiveConfigDir(), "vms", name)
+}
+
+// defaultConfigDir returns Tart's default config directory, ~/.tart.
+func defaultConfigDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".tart"), nil
 }
 
 // Returns the directory where we store our configuration
 func getConfigDir() string {
-	homeDir, err := os.UserHomeDir()
+	configDir, err := defaultConfigDir()
 	if err != nil {
 		panic(err)
 	}
-	configDir := filepath.Join(homeDir, ".tart")
 	if _, err := os.Stat(configDir); os.IsNotExist(err) {
 		os.Mkdir(configDir, 0700)
 	}
@@ -0,0 +1,364 @@
+package tart
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeTartScript is the shell script installed as tart by newFakeTart. It
+// records each invocation's arguments, separated by the unit separator, one
+// invocation per line written at once so concurrent calls don't interleave,
+// then runs the test's script. A list command the test's script doesn't
+// handle prints list.json, or an empty list.
+const fakeTartScript = `#!/bin/sh
+line=
+for a in "$@"; do line="$line$a$(printf '\037')"; done
+printf '%s\n' "$line" >> "$FAKE_TART_DIR/calls"
+{{SCRIPT}}
+if [ "$1" = list ]; then
+	if [ -f "$FAKE_TART_DIR/list.json" ]; then cat "$FAKE_TART_DIR/list.json"; else echo '[]'; fi
+	exit 0
+fi
+exit 0
+`
+
+// fakeTart is a Tart instance running a fake tart command.
+type fakeTart struct {
+	*Tart
+	dir string
+}
+
+// newFakeTart installs a fake tart command running script, in PATH for the
+// rest of the test, and returns an instance with a fresh config directory.
+// The script sees the fake's directory in FAKE_TART_DIR.
+func newFakeTart(t *testing.T, script string) *fakeTart {
+	t.Helper()
+	dir := t.TempDir()
+	body := strings.Replace(fakeTartScript, "{{SCRIPT}}", script, 1)
+	if err := os.WriteFile(filepath.Join(dir, "tart"), []byte(body), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("FAKE_TART_DIR", dir)
+	configDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(configDir, "vms"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return &fakeTart{Tart: &Tart{ConfigDir: configDir}, dir: dir}
+}
+
+// setList sets the JSON the fake prints for tart list.
+func (f *fakeTart) setList(t *testing.T, json string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(f.dir, "list.json"), []byte(json), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// calls returns the arguments of each invocation so far.
+func (f *fakeTart) calls(t *testing.T) [][]string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(f.dir, "calls"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	var calls [][]string
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		calls = append(calls, strings.Split(strings.TrimSuffix(line, "\x1f"), "\x1f"))
+	}
+	return calls
+}
+
+// commandCalls returns the invocations of the given tart subcommand.
+func (f *fakeTart) commandCalls(t *testing.T, command string) [][]string {
+	t.Helper()
+	var matched [][]string
+	for _, call := range f.calls(t) {
+		if len(call) > 0 && call[0] == command {
+			matched = append(matched, call)
+		}
+	}
+	return matched
+}
+
+// addVM creates a bundle directory with the given config.json for a VM.
+func (f *fakeTart) addVM(t *testing.T, name string, config string) {
+	t.Helper()
+	dir := f.vmDir(name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunStreamFloodedStderr(t *testing.T) {
+	f := newFakeTart(t, `
+if [ "$1" = flood ]; then
+	head -c 1048576 /dev/zero | tr '\0' e >&2
+	head -c 1048576 /dev/zero | tr '\0' o
+	exit 0
+fi`)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	output, err := f.runContext(ctx, "flood")
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(output) != 1048576 {
+		t.Errorf("got %d bytes of stdout, want 1048576", len(output))
+	}
+}
+
+func TestRunStreamCommandError(t *testing.T) {
+	f := newFakeTart(t, `
+if [ "$1" = fail ]; then
+	echo "something broke" >&2
+	exit 1
+fi`)
+	_, err := f.run("fail", "with space")
+	cmdErr, ok := err.(*CommandError)
+	if !ok {
+		t.Fatalf("error %v is not a *CommandError", err)
+	}
+	if !strings.Contains(cmdErr.Stderr, "something broke") {
+		t.Errorf("stderr = %q", cmdErr.Stderr)
+	}
+	want := "TART_HOME=" + shellQuote(f.ConfigDir) + " tart fail 'with space'"
+	if cmdErr.Command != want {
+		t.Errorf("command = %q, want %q", cmdErr.Command, want)
+	}
+}
+
+func TestRunStreamWriter(t *testing.T) {
+	f := newFakeTart(t, `
+if [ "$1" = hello ]; then
+	echo hello
+	exit 0
+fi`)
+	var b strings.Builder
+	output, err := f.runStream(context.Background(), nil, &b, "hello")
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if string(output) != "hello\n" || b.String() != "hello\n" {
+		t.Errorf("output = %q, streamed = %q", output, b.String())
+	}
+}
+
+func TestEffectiveConfigDir(t *testing.T) {
+	home := t.TempDir()
+	tests := []struct {
+		name      string
+		configDir string
+		tartHome  string
+		home      string
+		want      string
+	}{
+		{name: "config dir", configDir: "/configured", tartHome: "/env", home: home, want: "/configured"},
+		{name: "TART_HOME", tartHome: "/env", home: home, want: "/env"},
+		{name: "home", home: home, want: filepath.Join(home, ".tart")},
+		{name: "no home", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TART_HOME", tt.tartHome)
+			t.Setenv("HOME", tt.home)
+			tart := &Tart{ConfigDir: tt.configDir}
+			if got := tart.EffectiveConfigDir(); got != tt.want {
+				t.Errorf("EffectiveConfigDir = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if _, err := os.Stat(filepath.Join(home, ".tart")); !os.IsNotExist(err) {
+		t.Errorf("EffectiveConfigDir created ~/.tart: %v", err)
+	}
+}
+
+func TestConfigDirCheckedOnFirstCommand(t *testing.T) {
+	tests := []struct {
+		name      string
+		configDir func(f *fakeTart) string
+		wantErr   bool
+	}{
+		{name: "writable", configDir: func(f *fakeTart) string { return f.ConfigDir }},
+		{name: "missing", configDir: func(f *fakeTart) string { return filepath.Join(f.ConfigDir, "missing") }, wantErr: true},
+		{
+			name: "not a directory",
+			configDir: func(f *fakeTart) string {
+				path := filepath.Join(f.ConfigDir, "file")
+				os.WriteFile(path, nil, 0644)
+				return path
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeTart(t, `[ "$1" = --version ] && { echo 2.20.0; exit 0; }`)
+			tart := &Tart{ConfigDir: tt.configDir(f)}
+			_, err := tart.Version()
+			var dirErr *ConfigDirError
+			if errors.As(err, &dirErr) != tt.wantErr {
+				t.Fatalf("Version error = %v, want a *ConfigDirError: %v", err, tt.wantErr)
+			}
+			if calls := f.calls(t); (len(calls) == 0) != tt.wantErr {
+				t.Errorf("tart calls = %q", calls)
+			}
+		})
+	}
+}
+
+func TestNice(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("process priority is not supported on this platform")
+	}
+	// The priority is applied right after the process starts, so wait for it
+	f := newFakeTart(t, `
+if [ "$1" = niceness ]; then
+	i=0
+	while [ "$(nice)" != 19 ] && [ $i -lt 100 ]; do sleep 0.01; i=$((i+1)); done
+	nice
+	exit 0
+fi`)
+	f.Nice = 19
+	out, err := f.run("niceness")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(out)); got != "19" {
+		t.Errorf("niceness = %s, want 19", got)
+	}
+}
+
+func TestPrepareCmd(t *testing.T) {
+	f := newFakeTart(t, `
+if [ "$1" = env ]; then
+	echo "$TART_HOME $PREPARED"
+	exit 0
+fi`)
+	var args []string
+	f.PrepareCmd = func(cmd *exec.Cmd) {
+		args = cmd.Args[1:]
+		cmd.Env = append(cmd.Env, "PREPARED=yes")
+	}
+	out, err := f.run("env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := f.ConfigDir + " yes\n"; string(out) != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+	if len(args) != 1 || args[0] != "env" {
+		t.Errorf("PrepareCmd saw args %q, want [env]", args)
+	}
+}
+
+func TestClose(t *testing.T) {
+	tart := &Tart{}
+	var order []int
+	for i := 0; i < 3; i++ {
+		tart.onClose(func() error {
+			order = append(order, i)
+			if i == 1 {
+				return errors.New("busy")
+			}
+			return nil
+		})
+	}
+	err := tart.Close()
+	if err == nil || err.Error() != "busy" {
+		t.Errorf("Close = %v, want busy", err)
+	}
+	if want := []int{2, 1, 0}; !reflect.DeepEqual(order, want) {
+		t.Errorf("closers ran in order %v, want %v", order, want)
+	}
+	if err := tart.Close(); err != nil {
+		t.Errorf("second Close = %v, want nil", err)
+	}
+	if len(order) != 3 {
+		t.Errorf("closers ran %d times, want once each", len(order))
+	}
+}
+
+func TestWorkDir(t *testing.T) {
+	f := newFakeTart(t, `
+if [ "$1" = pwd ]; then pwd; exit 0; fi`)
+	f.WorkDir = t.TempDir()
+	out, err := f.run("pwd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := filepath.EvalSymlinks(f.WorkDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(out)); got != want {
+		t.Errorf("tart ran in %s, want %s", got, want)
+	}
+}
+
+func TestResolvePath(t *testing.T) {
+	tests := []struct {
+		workDir string
+		path    string
+		want    string
+	}{
+		{path: "disk.img", want: "disk.img"},
+		{workDir: "/work", path: "disk.img", want: "/work/disk.img"},
+		{workDir: "/work", path: "../disk.img", want: "/disk.img"},
+		{workDir: "/work", path: "/abs/disk.img", want: "/abs/disk.img"},
+	}
+	for _, tt := range tests {
+		tart := &Tart{WorkDir: tt.workDir}
+		if got := tart.resolvePath(tt.path); got != filepath.FromSlash(tt.want) {
+			t.Errorf("resolvePath(%q) with WorkDir %q = %q, want %q", tt.path, tt.workDir, got, tt.want)
+		}
+	}
+}
+
+func TestDefaultTimeout(t *testing.T) {
+	script := `
+if [ "$1" = slow ]; then
+	i=0
+	while [ $i -lt 10 ]; do sleep 0.05; i=$((i+1)); done
+	exit 0
+fi`
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		ctx     time.Duration
+		wantErr error
+	}{
+		{name: "default timeout", timeout: 100 * time.Millisecond, wantErr: context.DeadlineExceeded},
+		{name: "context deadline takes precedence", timeout: 100 * time.Millisecond, ctx: 10 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeTart(t, script)
+			f.DefaultTimeout = tt.timeout
+			ctx := context.Background()
+			if tt.ctx > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.ctx)
+				defer cancel()
+			}
+			_, err := f.runContext(ctx, "slow")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("runContext = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
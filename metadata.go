@@ -0,0 +1,195 @@
+package tart
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Tart has no native support for annotating VMs, so metadata is kept in a
+// MetadataStore, by default a sidecar file named <name>.meta.json in the
+// config directory.
+
+// MetadataStore stores the metadata attached to VMs, keyed by VM name. Set
+// Tart.Metadata to swap in another backend, such as a database shared by
+// several hosts. Implementations must be safe for concurrent use.
+type MetadataStore interface {
+	// List returns the names of the VMs with metadata, sorted.
+	List() ([]string, error)
+	// Get returns a VM's metadata, or an empty map if it has none.
+	Get(name string) (map[string]string, error)
+	// Set replaces a VM's metadata.
+	Set(name string, m map[string]string) error
+	// Delete removes a VM's metadata. Deleting metadata that doesn't exist
+	// isn't an error.
+	Delete(name string) error
+}
+
+// metadataSuffix is the suffix of the sidecar files FileMetadataStore writes.
+const metadataSuffix = ".meta.json"
+
+// metadataLocks holds a lock per FileMetadataStore directory, so stores for
+// the same directory serialize their access even across Tart instances.
+var metadataLocks sync.Map
+
+// FileMetadataStore stores each VM's metadata in a JSON sidecar file named
+// <name>.meta.json in Dir. Files are written atomically, so concurrent
+// readers, including other processes, never see a partial file.
+type FileMetadataStore struct {
+	Dir string `json:"dir"`
+	// FileMode is the permission mode of the sidecar files. Zero means 0600.
+	FileMode os.FileMode `json:"fileMode"`
+}
+
+// lock returns the lock for the store's directory.
+func (s FileMetadataStore) lock() *sync.RWMutex {
+	mu, _ := metadataLocks.LoadOrStore(filepath.Clean(s.Dir), &sync.RWMutex{})
+	return mu.(*sync.RWMutex)
+}
+
+// path returns the path of a VM's sidecar.
+func (s FileMetadataStore) path(name string) string {
+	return filepath.Join(s.Dir, name+metadataSuffix)
+}
+
+// List returns the names of the VMs with a sidecar in Dir, sorted.
+// It returns an error if the directory can't be read.
+func (s FileMetadataStore) List() ([]string, error) {
+	mu := s.lock()
+	mu.RLock()
+	defer mu.RUnlock()
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list VM metadata: %w", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if name, ok := strings.CutSuffix(entry.Name(), metadataSuffix); ok && entry.Type().IsRegular() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Get reads a VM's sidecar. A VM without one yields an empty map.
+// It returns an error if the sidecar can't be read or parsed.
+func (s FileMetadataStore) Get(name string) (map[string]string, error) {
+	mu := s.lock()
+	mu.RLock()
+	defer mu.RUnlock()
+	m := map[string]string{}
+	data, err := os.ReadFile(s.path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read VM metadata: %w", err)
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse VM metadata: %w", err)
+	}
+	return m, nil
+}
+
+// Set writes a VM's sidecar atomically.
+// It returns an error if the sidecar can't be written.
+func (s FileMetadataStore) Set(name string, m map[string]string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode VM metadata: %w", err)
+	}
+	mu := s.lock()
+	mu.Lock()
+	defer mu.Unlock()
+	mode := s.FileMode.Perm()
+	if mode == 0 {
+		mode = defaultFileMode
+	}
+	if err := writeFileMode(s.path(name), data, mode); err != nil {
+		return fmt.Errorf("failed to write VM metadata: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a VM's sidecar, if any.
+// It returns an error if the sidecar can't be removed.
+func (s FileMetadataStore) Delete(name string) error {
+	mu := s.lock()
+	mu.Lock()
+	defer mu.Unlock()
+	err := os.Remove(s.path(name))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete VM metadata: %w", err)
+	}
+	return nil
+}
+
+// metadata returns the metadata store in use.
+func (t *Tart) metadata() MetadataStore {
+	if t.Metadata != nil {
+		return t.Metadata
+	}
+	return FileMetadataStore{Dir: t.EffectiveConfigDir(), FileMode: t.FileMode}
+}
+
+// SetMetadata replaces the metadata attached to a local VM.
+// It returns an error wrapping ErrVMNotFound if the VM doesn't exist, or an
+// error if the metadata can't be written.
+func (t *Tart) SetMetadata(name string, m map[string]string) error {
+	exists, err := t.Exists(name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrVMNotFound, name)
+	}
+	return t.metadata().Set(name, m)
+}
+
+// GetMetadata retrieves the metadata attached to a local VM.
+// A VM without metadata yields an empty map.
+// It returns an error if the metadata can't be read.
+func (t *Tart) GetMetadata(name string) (map[string]string, error) {
+	return t.metadata().Get(name)
+}
+
+// deleteMetadata removes a VM's metadata and ID sidecar, if any.
+func (t *Tart) deleteMetadata(name string) error {
+	if err := t.metadata().Delete(name); err != nil {
+		return err
+	}
+	err := os.Remove(t.vmIDPath(name))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete VM metadata: %w", err)
+	}
+	return nil
+}
+
+// renameMetadata moves a VM's metadata and ID sidecar, if any, to a new
+// name.
+func (t *Tart) renameMetadata(oldName string, newName string) error {
+	store := t.metadata()
+	m, err := store.Get(oldName)
+	if err != nil {
+		return err
+	}
+	if len(m) > 0 {
+		if err := store.Set(newName, m); err != nil {
+			return err
+		}
+		if err := store.Delete(oldName); err != nil {
+			return err
+		}
+	}
+	err = os.Rename(t.vmIDPath(oldName), t.vmIDPath(newName))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to move VM metadata: %w", err)
+	}
+	return nil
+}
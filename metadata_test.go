@@ -0,0 +1,173 @@
+package tart
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestSetMetadata(t *testing.T) {
+	tests := []struct {
+		name    string
+		vm      string
+		wantErr error
+	}{
+		{name: "existing VM", vm: "vm"},
+		{name: "missing VM", vm: "missing", wantErr: ErrVMNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeTart(t, "")
+			f.setList(t, `[{"name":"vm","state":"stopped"}]`)
+			m := map[string]string{"owner": "ci"}
+			err := f.SetMetadata(tt.vm, m)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SetMetadata = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			got, err := f.GetMetadata(tt.vm)
+			if err != nil {
+				t.Fatalf("GetMetadata: %v", err)
+			}
+			if !reflect.DeepEqual(got, m) {
+				t.Errorf("GetMetadata = %v, want %v", got, m)
+			}
+		})
+	}
+}
+
+func TestFileMetadataStore(t *testing.T) {
+	s := FileMetadataStore{Dir: t.TempDir()}
+	if m, err := s.Get("a"); err != nil || len(m) != 0 {
+		t.Errorf("Get without a sidecar = %v, %v, want an empty map", m, err)
+	}
+	if err := s.Delete("a"); err != nil {
+		t.Errorf("Delete without a sidecar = %v, want nil", err)
+	}
+	for _, name := range []string{"b", "a"} {
+		if err := s.Set(name, map[string]string{"owner": name}); err != nil {
+			t.Fatalf("Set(%s): %v", name, err)
+		}
+	}
+	// Files other than sidecars aren't listed
+	if err := os.WriteFile(filepath.Join(s.Dir, "a.id"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	names, err := s.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if !reflect.DeepEqual(names, []string{"a", "b"}) {
+		t.Errorf("List = %q, want [a b]", names)
+	}
+	if m, err := s.Get("b"); err != nil || m["owner"] != "b" {
+		t.Errorf("Get(b) = %v, %v, want owner=b", m, err)
+	}
+	info, err := os.Stat(s.path("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("sidecar mode = %v, want 0600", info.Mode().Perm())
+	}
+	if err := s.Delete("a"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if names, _ := s.List(); !reflect.DeepEqual(names, []string{"b"}) {
+		t.Errorf("List after Delete = %q, want [b]", names)
+	}
+}
+
+func TestFileMetadataStoreConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Separate stores for the same directory share a lock
+			s := FileMetadataStore{Dir: dir}
+			if err := s.Set("vm", map[string]string{"n": strconv.Itoa(i)}); err != nil {
+				t.Error(err)
+			}
+			if _, err := s.Get("vm"); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+// memoryMetadataStore is a MetadataStore backed by a map.
+type memoryMetadataStore struct {
+	mu sync.Mutex
+	m  map[string]map[string]string
+}
+
+func (s *memoryMetadataStore) List() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	for name := range s.m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *memoryMetadataStore) Get(name string) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := map[string]string{}
+	for k, v := range s.m[name] {
+		m[k] = v
+	}
+	return m, nil
+}
+
+func (s *memoryMetadataStore) Set(name string, m map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m[name] = m
+	return nil
+}
+
+func (s *memoryMetadataStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.m, name)
+	return nil
+}
+
+func TestCustomMetadataStore(t *testing.T) {
+	f := newFakeTart(t, `
+if [ "$1" = rename ]; then
+	echo "[{\"name\":\"$3\",\"state\":\"stopped\"}]" > "$FAKE_TART_DIR/list.json"
+	exit 0
+fi`)
+	store := &memoryMetadataStore{m: map[string]map[string]string{}}
+	f.Metadata = store
+	f.setList(t, `[{"name":"old","state":"stopped"}]`)
+	if err := f.SetMetadata("old", map[string]string{"owner": "ci"}); err != nil {
+		t.Fatalf("SetMetadata: %v", err)
+	}
+	if _, err := f.Rename("old", "new"); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	if names, _ := store.List(); !reflect.DeepEqual(names, []string{"new"}) {
+		t.Errorf("store holds %q, want the renamed VM only", names)
+	}
+	if m, err := f.GetMetadata("new"); err != nil || m["owner"] != "ci" {
+		t.Errorf("GetMetadata = %v, %v, want owner=ci", m, err)
+	}
+	if sidecars, _ := filepath.Glob(filepath.Join(f.ConfigDir, "*"+metadataSuffix)); len(sidecars) != 0 {
+		t.Errorf("sidecars written despite the custom store: %q", sidecars)
+	}
+}
@@ -1,6 +1,17 @@
 package tart
 
-import "fmt"
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
 
 // VMConfig represents the parameters of a VM.
 type VMConfig struct {
@@ -18,9 +29,8 @@ type VMConfig struct {
 	} `json:"display"`
 }
 
-// SetConfig modifies a VM's configuration.
-// It returns an error if the configuration update process fails.
-func (t *Tart) SetConfig(name string, config VMConfig) error {
+// setConfigArgs builds the arguments for modifying a VM's configuration.
+func setConfigArgs(name string, config VMConfig) []string {
 	args := []string{"set", name}
 	if config.CPUCount > 0 {
 		args = append(args, "--cpu", fmt.Sprintf("%d", config.CPUCount))
@@ -34,6 +44,30 @@ func (t *Tart) SetConfig(name string, config VMConfig) error {
 	if config.MACAddress == "random" {
 		args = append(args, "--random-mac")
 	}
+	return args
+}
+
+// SetConfig modifies a VM's configuration.
+// Zero-valued fields are left unchanged, so an empty config is a no-op.
+// A MACAddress of "random" generates a new address; any other non-empty
+// value must be a unicast MAC address and is written to the VM's
+// configuration directly, since Tart can't set a specific address.
+// It returns an error if the configuration update process fails.
+func (t *Tart) SetConfig(name string, config VMConfig) error {
+	if config.MACAddress != "" && config.MACAddress != "random" {
+		mac, err := checkUnicastMAC(config.MACAddress)
+		if err != nil {
+			return err
+		}
+		if err := t.setMACAddress(name, mac); err != nil {
+			return err
+		}
+	}
+	args := setConfigArgs(name, config)
+	if len(args) == 2 {
+		// Nothing to change
+		return nil
+	}
 	output, err := t.run(args...)
 	if err != nil {
 		return fmt.Errorf("failed to set VM configuration: %w, output: %s", err, string(output))
@@ -41,6 +75,85 @@ func (t *Tart) SetConfig(name string, config VMConfig) error {
 	return nil
 }
 
+// setMACAddress rewrites the MAC address in a VM's config.json, leaving the
+// other fields, including ones VMConfig doesn't model, untouched.
+func (t *Tart) setMACAddress(name string, mac string) error {
+	if err := t.requireLocal("setting a specific MAC address"); err != nil {
+		return err
+	}
+	path := filepath.Join(t.vmDir(name), "config.json")
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to read VM configuration: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read VM configuration: %w", err)
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("failed to parse VM configuration: %w", err)
+	}
+	raw["macAddress"], _ = json.Marshal(mac)
+	data, err = json.Marshal(raw)
+	if err != nil {
+		return fmt.Errorf("failed to encode VM configuration: %w", err)
+	}
+	return replaceConfigFile(path, data, info.Mode().Perm())
+}
+
+// replaceConfigFile atomically replaces a VM's config.json. Tart owns the
+// file, so its existing mode is kept rather than the instance's FileMode.
+func replaceConfigFile(path string, data []byte, mode os.FileMode) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, mode); err != nil {
+		return fmt.Errorf("failed to write VM configuration: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write VM configuration: %w", err)
+	}
+	return nil
+}
+
+// SetConfigAtomic is like SetConfig, but either applies every field or none.
+// Tart only reads a VM's configuration when it starts, so the VM must be
+// stopped or suspended; this is checked before anything is changed. The
+// configuration file is saved beforehand and put back if any step fails.
+// It returns an error if the VM is running or doesn't exist, or if the
+// update fails, joined with the rollback's error if that fails too.
+func (t *Tart) SetConfigAtomic(name string, config VMConfig) error {
+	if err := t.requireLocal("SetConfigAtomic"); err != nil {
+		return err
+	}
+	s, err := t.State(name)
+	if err != nil {
+		return fmt.Errorf("failed to get VM state: %w", err)
+	}
+	if s.Name != name {
+		return fmt.Errorf("%w: %s", ErrVMNotFound, name)
+	}
+	if s.State == StateRunning {
+		return fmt.Errorf("VM %s must be stopped to change its configuration", name)
+	}
+	path := filepath.Join(t.vmDir(name), "config.json")
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to read VM configuration: %w", err)
+	}
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read VM configuration: %w", err)
+	}
+	if err := t.SetConfig(name, config); err != nil {
+		if rollbackErr := replaceConfigFile(path, saved, info.Mode().Perm()); rollbackErr != nil {
+			return errors.Join(err, fmt.Errorf("failed to roll back VM configuration: %w", rollbackErr))
+		}
+		return err
+	}
+	return nil
+}
+
 // GetConfig retrieves a VM's configuration.
 // It returns the configuration as a string and an error if the retrieval process fails.
 func (t *Tart) GetConfig(name string, format string) (string, error) {
@@ -55,14 +168,78 @@ func (t *Tart) GetConfig(name string, format string) (string, error) {
 	return string(output), nil
 }
 
-// Rename renames a local VM.
-// It returns an error if the rename process fails.
-func (t *Tart) Rename(oldName string, newName string) error {
+// Config retrieves and parses a VM's configuration from its bundle.
+// The bundle records memory sizes in bytes; they're converted to megabytes
+// to match the units SetConfig expects.
+// It returns an error if the configuration can't be read or parsed.
+func (t *Tart) Config(name string) (VMConfig, error) {
+	var config VMConfig
+	if err := t.requireLocal("Config"); err != nil {
+		return config, err
+	}
+	data, err := os.ReadFile(filepath.Join(t.vmDir(name), "config.json"))
+	if err != nil {
+		return config, fmt.Errorf("failed to read VM configuration: %w", err)
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("failed to parse VM configuration: %w", err)
+	}
+	config.MemorySize /= 1024 * 1024
+	config.MemorySizeMin /= 1024 * 1024
+	return config, nil
+}
+
+// Rename renames a local VM, moving any metadata attached to it.
+// It returns the renamed VM's state, an error wrapping ErrVMNotFound if the
+// VM doesn't exist, one wrapping ErrVMAlreadyExists if the new name is
+// taken, or an error if the rename process fails.
+func (t *Tart) Rename(oldName string, newName string) (VMState, error) {
+	var renamed VMState
+	localVMs, err := t.List(ListOptions{})
+	if err != nil {
+		return renamed, fmt.Errorf("failed to list local VMs: %w", err)
+	}
+	found := false
+	for _, existingVM := range localVMs {
+		switch existingVM.Name {
+		case oldName:
+			found = true
+		case newName:
+			return renamed, fmt.Errorf("%w: %s", ErrVMAlreadyExists, newName)
+		}
+	}
+	if !found {
+		return renamed, fmt.Errorf("%w: %s", ErrVMNotFound, oldName)
+	}
 	output, err := t.run("rename", oldName, newName)
 	if err != nil {
-		return fmt.Errorf("failed to rename VM: %w, output: %s", err, string(output))
+		return renamed, fmt.Errorf("failed to rename VM: %w, output: %s", err, string(output))
 	}
-	return nil
+	if err := t.renameMetadata(oldName, newName); err != nil {
+		return renamed, err
+	}
+	return t.State(newName)
+}
+
+// CreatedAt returns when a local VM's bundle directory was created in the
+// config directory, for age-based cleanup. Tart doesn't record creation
+// times, so this is a file system timestamp: the directory's birth time on
+// macOS, or its modification time where that's unavailable, which also
+// changes whenever files are added to or removed from the bundle. Cloning
+// or importing a VM creates a new bundle, resetting its age.
+// It returns an error wrapping ErrVMNotFound if the VM doesn't exist.
+func (t *Tart) CreatedAt(name string) (time.Time, error) {
+	if err := t.requireLocal("CreatedAt"); err != nil {
+		return time.Time{}, err
+	}
+	info, err := os.Stat(t.vmDir(name))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return time.Time{}, fmt.Errorf("%w: %s", ErrVMNotFound, name)
+		}
+		return time.Time{}, fmt.Errorf("failed to inspect VM: %w", err)
+	}
+	return birthTime(info), nil
 }
 
 // CreateOptions represents the configuration for creating a new VM.
@@ -70,21 +247,81 @@ type CreateOptions struct {
 	FromIPSW string `json:"fromIPSW"`
 	Linux    bool   `json:"linux"`
 	DiskSize int    `json:"diskSize"`
+	// FromDisk is the path of an existing disk image, such as one produced
+	// by other tooling, to use as the new VM's root disk. Tart releases
+	// that don't accept --from-disk for create make Create fail with
+	// ErrUnsupported; use Import for a VM exported as a .tvm file.
+	FromDisk string `json:"fromDisk"`
 }
 
-// Create creates a new VM and returns it.
-// It returns an error if a VM with the same name already exists or if the creation process fails.
-func (t *Tart) Create(name string, options CreateOptions) error {
-	// Check if the VM name is already taken
-	localVMs, err := t.List(ListOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to list local VMs: %w", err)
+// Validate checks the options for problems that would only surface after
+// Tart has started creating the VM. A local IPSW path is resolved against
+// the current directory.
+// It returns a *ValidationError listing every problem found, or nil.
+func (o CreateOptions) Validate() error {
+	return o.validate(func(p string) string { return p })
+}
+
+// validate is like Validate, but passes local paths through resolve before
+// checking them. A nil resolve skips the checks that touch the file system.
+func (o CreateOptions) validate(resolve func(string) string) error {
+	var problems []error
+	var sources []string
+	if o.FromIPSW != "" {
+		sources = append(sources, "FromIPSW")
+	}
+	if o.Linux {
+		sources = append(sources, "Linux")
+	}
+	if o.FromDisk != "" {
+		sources = append(sources, "FromDisk")
+	}
+	switch {
+	case len(sources) > 1:
+		problems = append(problems, fmt.Errorf("%s are mutually exclusive", strings.Join(sources, ", ")))
+	case len(sources) == 0:
+		problems = append(problems, errors.New("one of FromIPSW, Linux or FromDisk is required"))
+	}
+	if o.FromDisk != "" && resolve != nil {
+		info, err := os.Stat(resolve(o.FromDisk))
+		switch {
+		case err != nil:
+			problems = append(problems, fmt.Errorf("disk image %s is not readable: %w", o.FromDisk, err))
+		case !info.Mode().IsRegular():
+			problems = append(problems, fmt.Errorf("disk image %s is not a regular file", o.FromDisk))
+		}
 	}
-	for _, existingVM := range localVMs {
-		if existingVM.Name == name {
-			return fmt.Errorf("VM with name %s already exists", name)
+	if o.DiskSize < 0 {
+		problems = append(problems, fmt.Errorf("invalid disk size: %d", o.DiskSize))
+	}
+	if o.FromIPSW != "" && o.FromIPSW != "latest" && !isURL(o.FromIPSW) && resolve != nil {
+		f, err := os.Open(resolve(o.FromIPSW))
+		if err != nil {
+			problems = append(problems, fmt.Errorf("IPSW %s is not readable: %w", o.FromIPSW, err))
+		} else {
+			f.Close()
 		}
 	}
+	return validationError(problems)
+}
+
+// hasSource reports whether the options set any source for the new VM.
+func (o CreateOptions) hasSource() bool {
+	return o.FromIPSW != "" || o.Linux || o.FromDisk != ""
+}
+
+// isURL reports whether s is an HTTP(S) URL rather than a local path.
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "https://") || strings.HasPrefix(s, "http://")
+}
+
+// createArgs builds the arguments for creating a VM, without checking the
+// files the options refer to.
+// It returns an error if the options are invalid.
+func (t *Tart) createArgs(name string, options CreateOptions) ([]string, error) {
+	if err := options.validate(nil); err != nil {
+		return nil, err
+	}
 	args := []string{"create", name}
 	if options.FromIPSW != "" {
 		args = append(args, "--from-ipsw", options.FromIPSW)
@@ -92,11 +329,71 @@ func (t *Tart) Create(name string, options CreateOptions) error {
 	if options.Linux {
 		args = append(args, "--linux")
 	}
+	if options.FromDisk != "" {
+		args = append(args, "--from-disk", options.FromDisk)
+	}
 	if options.DiskSize > 0 {
 		args = append(args, "--disk-size", fmt.Sprintf("%d", options.DiskSize))
 	}
+	return args, nil
+}
+
+// checkNameFree returns an error wrapping ErrVMAlreadyExists if a local VM
+// with the given name exists, unless prechecks are disabled.
+func (t *Tart) checkNameFree(name string) error {
+	if t.SkipPrecheck {
+		return nil
+	}
+	localVMs, err := t.List(ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list local VMs: %w", err)
+	}
+	for _, existingVM := range localVMs {
+		if existingVM.Name == name {
+			return fmt.Errorf("%w: %s", ErrVMAlreadyExists, name)
+		}
+	}
+	return nil
+}
+
+// existsError maps a failed create, clone or import to ErrVMAlreadyExists
+// when Tart rejected it because the name is taken.
+func existsError(err error, name string) error {
+	if strings.Contains(commandStderr(err), "already exists") {
+		return fmt.Errorf("%w: %s", ErrVMAlreadyExists, name)
+	}
+	return nil
+}
+
+// Create creates a new VM and returns it.
+// It returns an error if the IPSW isn't readable, a VM with the same name already exists or if the creation process fails.
+func (t *Tart) Create(name string, options CreateOptions) error {
+	if err := options.validate(t.resolvePath); err != nil {
+		return err
+	}
+	args, err := t.createArgs(name, options)
+	if err != nil {
+		return err
+	}
+	if options.FromDisk != "" {
+		flags, err := t.supportedFlags("create")
+		if err != nil {
+			return err
+		}
+		if !flags["--from-disk"] {
+			version, _ := t.Version()
+			return fmt.Errorf("%w: tart %s create can't create a VM from a disk image", ErrUnsupported, version)
+		}
+	}
+	// Check if the VM name is already taken
+	if err := t.checkNameFree(name); err != nil {
+		return err
+	}
 	output, err := t.run(args...)
 	if err != nil {
+		if existsErr := existsError(err, name); existsErr != nil {
+			return existsErr
+		}
 		return fmt.Errorf("failed to create VM: %w, output: %s", err, string(output))
 	}
 	return nil
@@ -107,35 +404,153 @@ type CloneOptions struct {
 	NewName     string `json:"newName"`
 	Insecure    bool   `json:"insecure"`
 	Concurrency int    `json:"concurrency"`
+	// Deduplicate expands a remote image using APFS copy-on-write clones of
+	// the layers in the local OCI cache instead of copying them.
+	Deduplicate bool `json:"deduplicate"`
+	// PruneLimit is the amount of space in gigabytes Tart keeps free when it
+	// automatically prunes the cache to make room for the clone. Zero uses
+	// Tart's default.
+	PruneLimit int `json:"pruneLimit"`
+	// CopyMetadata copies the source VM's metadata to the clone. A source
+	// without metadata, such as a remote image, contributes nothing.
+	CopyMetadata bool `json:"copyMetadata"`
+	// Metadata is merged over any copied metadata and attached to the clone.
+	Metadata map[string]string `json:"metadata"`
+	// IncludeState makes a stateful clone of a suspended local VM: the
+	// source's saved memory state is copied along with its disk, so the
+	// clone resumes where the source was suspended instead of booting
+	// afresh as a clean clone does. The source must be suspended.
+	IncludeState bool `json:"includeState"`
+	// Credentials, if set, authenticate this clone only.
+	Credentials *Credentials `json:"-"`
+	// RandomizeMAC gives the clone a new random MAC address instead of the
+	// source's, so that clones running at the same time don't get the same
+	// IP address. It can't be combined with IncludeState, since a resumed
+	// VM expects the address it was suspended with.
+	RandomizeMAC bool `json:"randomizeMAC"`
+}
+
+// cloneArgs builds the arguments for cloning a VM.
+// It returns an error if the options are invalid.
+func (t *Tart) cloneArgs(sourceName string, newName string, options CloneOptions) ([]string, error) {
+	concurrency, _, err := t.transferDefaults(options.Concurrency, 0)
+	if err != nil {
+		return nil, err
+	}
+	if options.PruneLimit < 0 {
+		return nil, fmt.Errorf("invalid prune limit: %d", options.PruneLimit)
+	}
+	if options.RandomizeMAC && options.IncludeState {
+		return nil, errors.New("RandomizeMAC can't be combined with IncludeState")
+	}
+	args := []string{"clone", sourceName, newName}
+	if options.Insecure {
+		args = append(args, "--insecure")
+	}
+	if concurrency > 0 {
+		args = append(args, "--concurrency", fmt.Sprintf("%d", concurrency))
+	}
+	if options.Deduplicate {
+		args = append(args, "--deduplicate")
+	}
+	if options.PruneLimit > 0 {
+		args = append(args, "--prune-limit", fmt.Sprintf("%d", options.PruneLimit))
+	}
+	return args, nil
 }
 
 // Clone clones an existing VM.
 // It returns an error if a VM with the new name already exists or if the cloning process fails.
 func (t *Tart) Clone(sourceName string, newName string, options CloneOptions) error {
+	args, err := t.cloneArgs(sourceName, newName, options)
+	if err != nil {
+		return err
+	}
 	// Check if the new VM name is already taken
-	localVMs, err := t.List(ListOptions{})
+	if err := t.checkNameFree(newName); err != nil {
+		return err
+	}
+	if options.IncludeState {
+		if err := t.requireLocal("cloning a VM's state"); err != nil {
+			return err
+		}
+		s, err := t.State(sourceName)
+		if err != nil {
+			return fmt.Errorf("failed to get VM state: %w", err)
+		}
+		if s.State != StateSuspended {
+			return fmt.Errorf("VM %s must be suspended to clone its state, but is %s", sourceName, s.State)
+		}
+	}
+	ctx, done := t.trackPull(context.Background(), sourceName)
+	defer done()
+	output, err := t.runEnv(ctx, options.Credentials.env(), args...)
 	if err != nil {
-		return fmt.Errorf("failed to list local VMs: %w", err)
+		if existsErr := existsError(err, newName); existsErr != nil {
+			return existsErr
+		}
+		return fmt.Errorf("failed to clone VM: %w, output: %s", err, string(output))
 	}
-	for _, existingVM := range localVMs {
-		if existingVM.Name == newName {
-			return fmt.Errorf("VM with name %s already exists", newName)
+	if options.IncludeState {
+		if err := t.cloneState(sourceName, newName); err != nil {
+			return err
 		}
 	}
-	args := []string{"clone", sourceName, newName}
-	if options.Insecure {
-		args = append(args, "--insecure")
+	if options.RandomizeMAC {
+		if err := t.SetConfig(newName, VMConfig{MACAddress: "random"}); err != nil {
+			return err
+		}
 	}
-	if options.Concurrency > 0 {
-		args = append(args, "--concurrency", fmt.Sprintf("%d", options.Concurrency))
+	return t.cloneMetadata(sourceName, newName, options)
+}
+
+// cloneState copies a suspended VM's saved state into its clone's bundle.
+func (t *Tart) cloneState(sourceName string, newName string) error {
+	dst := filepath.Join(t.vmDir(newName), suspendStateFile)
+	if _, err := os.Stat(dst); err == nil {
+		// Tart already carried the state over
+		return nil
+	}
+	src, err := os.Open(filepath.Join(t.vmDir(sourceName), suspendStateFile))
+	if err != nil {
+		return fmt.Errorf("failed to read VM state: %w", err)
 	}
-	output, err := t.run(args...)
+	defer src.Close()
+	out, err := t.createFile(dst, true)
 	if err != nil {
-		return fmt.Errorf("failed to clone VM: %w, output: %s", err, string(output))
+		return fmt.Errorf("failed to write VM state: %w", err)
+	}
+	if _, err := io.Copy(out, src); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return fmt.Errorf("failed to write VM state: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return fmt.Errorf("failed to write VM state: %w", err)
 	}
 	return nil
 }
 
+// cloneMetadata attaches metadata to a freshly cloned VM.
+func (t *Tart) cloneMetadata(sourceName string, newName string, options CloneOptions) error {
+	m := map[string]string{}
+	if options.CopyMetadata {
+		source, err := t.GetMetadata(sourceName)
+		if err != nil {
+			return err
+		}
+		m = source
+	}
+	for k, v := range options.Metadata {
+		m[k] = v
+	}
+	if len(m) == 0 {
+		return nil
+	}
+	return t.metadata().Set(newName, m)
+}
+
 // ImportOptions represents the configuration for importing an IPSW.
 type ImportOptions struct {
 	Path string `json:"path"`
@@ -145,36 +560,189 @@ type ImportOptions struct {
 // It returns an error if a VM with the same name already exists or if the import process fails.
 func (t *Tart) Import(path string, name string) error {
 	// Check if the VM name is already taken
-	localVMs, err := t.List(ListOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to list local VMs: %w", err)
-	}
-	for _, existingVM := range localVMs {
-		if existingVM.Name == name {
-			return fmt.Errorf("VM with name %s already exists", name)
-		}
+	if err := t.checkNameFree(name); err != nil {
+		return err
 	}
 	output, err := t.run("import", path, name)
 	if err != nil {
+		if existsErr := existsError(err, name); existsErr != nil {
+			return existsErr
+		}
 		return fmt.Errorf("failed to import VM: %w, output: %s", err, string(output))
 	}
 	return nil
 }
 
-// Export exports a VM to a compressed .tvm file.
-// It returns an error if the export process fails.
-func (t *Tart) Export(name string, path string) error {
+// ImportFrom imports a VM from a compressed .tvm stream read from r.
+// Tart can only import from a file, so the stream is staged in a temporary
+// directory that is removed afterwards; the temporary directory must have
+// room for the whole archive.
+// It returns an error wrapping ErrUnsupported with a remote Transport, which
+// couldn't read the staged file, an error if a VM with the same name already
+// exists or if the import process fails.
+func (t *Tart) ImportFrom(r io.Reader, name string) error {
+	if err := t.requireLocal("ImportFrom"); err != nil {
+		return err
+	}
+	// Check before staging so a taken name fails fast
+	if err := t.checkNameFree(name); err != nil {
+		return err
+	}
+	dir, err := os.MkdirTemp("", "go-tart-import-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "vm.tvm")
+	f, err := t.createFile(path, false)
+	if err != nil {
+		return fmt.Errorf("failed to stage VM archive: %w", err)
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stage VM archive: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to stage VM archive: %w", err)
+	}
+	return t.Import(path, name)
+}
+
+// Constants representing the compression of an exported .tvm archive.
+const (
+	// CompressionDefault uses Tart's built-in compression.
+	CompressionDefault = ""
+	// CompressionNone writes an uncompressed archive: fastest, largest.
+	CompressionNone = "none"
+	// CompressionLZ4 favors speed over size.
+	CompressionLZ4 = "lz4"
+	// CompressionZstd favors size over speed.
+	CompressionZstd = "zstd"
+)
+
+// ExportOptions represents the options for exporting a VM.
+type ExportOptions struct {
+	// Compression is one of the Compression constants. Tart releases to
+	// date always use their built-in compression, so any other value fails
+	// with ErrUnsupported unless `tart export` accepts a --compression flag.
+	// Import detects the compression of an archive by itself.
+	Compression string `json:"compression"`
+}
+
+// exportArgs builds the arguments for exporting a VM.
+// It returns an error if the options are invalid.
+func exportArgs(name string, path string, options ExportOptions) ([]string, error) {
 	args := []string{"export", name}
 	if path != "" {
 		args = append(args, path)
 	}
-	output, err := t.run(args...)
+	switch options.Compression {
+	case CompressionDefault:
+	case CompressionNone, CompressionLZ4, CompressionZstd:
+		args = append(args, "--compression", options.Compression)
+	default:
+		return nil, fmt.Errorf("invalid compression: %s", options.Compression)
+	}
+	return args, nil
+}
+
+// Export exports a VM to a compressed .tvm file.
+// It returns an error if the export process fails.
+func (t *Tart) Export(name string, path string) error {
+	return t.ExportContext(context.Background(), name, path)
+}
+
+// ExportContext is like Export, but cancelling the context aborts the export.
+// A file left behind by a failed or aborted export is removed, unless it
+// existed before the export started or the export ran on a remote host.
+// It returns an error if the export process fails.
+func (t *Tart) ExportContext(ctx context.Context, name string, path string) error {
+	return t.ExportWithOptions(ctx, name, path, ExportOptions{})
+}
+
+// ExportWithOptions is like ExportContext with the specified options.
+// It returns an error wrapping ErrUnsupported if the installed Tart can't
+// apply the options, or an error if they're invalid or the export process
+// fails.
+func (t *Tart) ExportWithOptions(ctx context.Context, name string, path string, options ExportOptions) error {
+	args, err := exportArgs(name, path, options)
 	if err != nil {
+		return err
+	}
+	if options.Compression != CompressionDefault {
+		flags, err := t.supportedFlags("export")
+		if err != nil {
+			return err
+		}
+		if !flags["--compression"] {
+			version, _ := t.Version()
+			return fmt.Errorf("%w: tart %s export doesn't accept --compression", ErrUnsupported, version)
+		}
+	}
+	if t.remote() {
+		// The export is written on the remote host, out of reach of the
+		// cleanup and permission handling below.
+		output, err := t.runContext(ctx, args...)
+		if err != nil {
+			return fmt.Errorf("failed to export VM: %w, output: %s", err, string(output))
+		}
+		return nil
+	}
+	target := path
+	if target == "" {
+		target = name + ".tvm"
+	}
+	target = t.resolvePath(target)
+	_, statErr := os.Stat(target)
+	existed := statErr == nil
+	output, err := t.runContext(ctx, args...)
+	if err != nil {
+		if !existed {
+			os.Remove(target)
+		}
 		return fmt.Errorf("failed to export VM: %w, output: %s", err, string(output))
 	}
+	if err := os.Chmod(target, t.fileMode()); err != nil {
+		return fmt.Errorf("failed to set exported VM permissions: %w", err)
+	}
+	return nil
+}
+
+// ExportTo exports a VM as a compressed .tvm stream written to w.
+// Tart can only export to a file, so the archive is staged in a temporary
+// directory that is removed afterwards; the temporary directory must have
+// room for the whole export.
+// It returns an error wrapping ErrUnsupported with a remote Transport, which
+// would write the staged file on the remote host, or an error if the export
+// process fails or the stream can't be written.
+func (t *Tart) ExportTo(name string, w io.Writer) error {
+	if err := t.requireLocal("ExportTo"); err != nil {
+		return err
+	}
+	dir, err := os.MkdirTemp("", "go-tart-export-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "vm.tvm")
+	if err := t.Export(name, path); err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open exported VM: %w", err)
+	}
+	defer f.Close()
+	if _, err := io.Copy(w, f); err != nil {
+		return fmt.Errorf("failed to write exported VM: %w", err)
+	}
 	return nil
 }
 
+// suspendStateFile is the name of the file in a VM's bundle holding the
+// memory state saved by Suspend.
+const suspendStateFile = "state.vzvmsave"
+
 // Suspend suspends a VM.
 // It returns an error if the suspension process fails.
 func (t *Tart) Suspend(name string) error {
@@ -185,28 +753,143 @@ func (t *Tart) Suspend(name string) error {
 	return nil
 }
 
-// Stop stops a VM.
-// It returns an error if the stop process fails.
-func (t *Tart) Stop(name string, timeout int) error {
+// SuspendFile describes the memory state saved when a VM run with the
+// Suspendable option is suspended.
+type SuspendFile struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+}
+
+// SuspendFile returns the location and size of a VM's saved suspend state.
+// Tart always keeps it in the VM's bundle and can't relocate it.
+// It returns an error wrapping ErrVMNotFound if the VM doesn't exist, one
+// wrapping ErrNoSuspendState if it has no saved state, or an error if the
+// file can't be inspected.
+func (t *Tart) SuspendFile(name string) (SuspendFile, error) {
+	var ret SuspendFile
+	if err := t.requireLocal("SuspendFile"); err != nil {
+		return ret, err
+	}
+	if _, err := os.Stat(t.vmDir(name)); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return ret, fmt.Errorf("%w: %s", ErrVMNotFound, name)
+		}
+		return ret, fmt.Errorf("failed to inspect VM: %w", err)
+	}
+	path := filepath.Join(t.vmDir(name), suspendStateFile)
+	info, err := os.Stat(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return ret, fmt.Errorf("%w: %s", ErrNoSuspendState, name)
+		}
+		return ret, fmt.Errorf("failed to inspect suspend state: %w", err)
+	}
+	return SuspendFile{Path: path, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+// DiscardSuspendState deletes a stopped VM's saved suspend state so that its
+// next run boots from scratch instead of resuming.
+// It returns an error wrapping ErrNoSuspendState if it has no saved state,
+// or an error if the VM is running or the file can't be removed.
+func (t *Tart) DiscardSuspendState(name string) error {
+	file, err := t.SuspendFile(name)
+	if err != nil {
+		return err
+	}
+	running, err := t.Running(name)
+	if err != nil {
+		return err
+	}
+	if running {
+		return fmt.Errorf("VM %s is running", name)
+	}
+	if err := os.Remove(file.Path); err != nil {
+		return fmt.Errorf("failed to remove suspend state: %w", err)
+	}
+	return nil
+}
+
+// stopArgs builds the arguments for stopping a VM.
+func stopArgs(name string, timeout int) []string {
 	args := []string{"stop", name}
 	if timeout > 0 {
 		args = append(args, "--timeout", fmt.Sprintf("%d", timeout))
 	}
-	output, err := t.run(args...)
+	return args
+}
+
+// Stop stops a VM.
+// It returns an error wrapping ErrVMNotRunning if the VM is already stopped,
+// one wrapping ErrVMNotFound if it doesn't exist, or an error if the stop
+// process fails. Use StopIfRunning to treat a stopped VM as success.
+func (t *Tart) Stop(name string, timeout int) error {
+	s, err := t.State(name)
+	if err != nil {
+		return fmt.Errorf("failed to get VM state: %w", err)
+	}
+	if s.Name != name {
+		return fmt.Errorf("%w: %s", ErrVMNotFound, name)
+	}
+	if s.State == StateStopped {
+		return fmt.Errorf("%w: %s", ErrVMNotRunning, name)
+	}
+	output, err := t.run(stopArgs(name, timeout)...)
 	if err != nil {
 		return fmt.Errorf("failed to stop VM: %w, output: %s", err, string(output))
 	}
 	return nil
 }
 
-// Delete deletes a VM.
-// It returns an error if the deletion process fails.
+// StopIfRunning stops a VM unless it's already stopped.
+// It returns an error if the VM doesn't exist or the stop process fails.
+func (t *Tart) StopIfRunning(name string, timeout int) error {
+	err := t.Stop(name, timeout)
+	if errors.Is(err, ErrVMNotRunning) {
+		return nil
+	}
+	return err
+}
+
+// StopAndWait stops a VM unless it's already stopped, then waits until it
+// has fully shut down, so that operations such as Delete or SetConfig don't
+// race a VM that's still exiting. The timeout is passed to Stop; the context
+// bounds the whole wait, which polls with the instance's PollBackoff and, if
+// this instance started the VM, also waits for its process to exit.
+// It returns a *StopTimeoutError if the context is done before the VM has
+// stopped, or an error if the VM doesn't exist or the stop process fails.
+func (t *Tart) StopAndWait(ctx context.Context, name string, timeout int) error {
+	if err := t.StopIfRunning(name, timeout); err != nil {
+		return err
+	}
+	if h, ok := t.handle(name); ok {
+		select {
+		case <-h.Done():
+		case <-ctx.Done():
+			return &StopTimeoutError{Name: name, Err: ctx.Err()}
+		}
+	}
+	if err := t.WaitForState(ctx, name, StateStopped); err != nil {
+		if ctx.Err() != nil {
+			return &StopTimeoutError{Name: name, Err: ctx.Err()}
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete deletes a VM along with any metadata attached to it.
+// It returns an error wrapping ErrVMNotFound if the VM doesn't exist, or an
+// error if the deletion process otherwise fails.
 func (t *Tart) Delete(name string) error {
 	output, err := t.run("delete", name)
 	if err != nil {
+		if strings.Contains(commandStderr(err), "does not exist") {
+			return fmt.Errorf("%w: %s", ErrVMNotFound, name)
+		}
 		return fmt.Errorf("failed to delete VM: %w, output: %s", err, string(output))
 	}
-	return nil
+	return t.deleteMetadata(name)
 }
 
 // PruneOptions represents the options for pruning.
@@ -216,9 +899,8 @@ type PruneOptions struct {
 	SpaceBudget int    `json:"spaceBudget"`
 }
 
-// Prune prunes OCI and IPSW caches or local VMs.
-// It returns an error if the pruning process fails.
-func (t *Tart) Prune(options PruneOptions) error {
+// pruneArgs builds the arguments for pruning.
+func pruneArgs(options PruneOptions) []string {
 	args := []string{"prune"}
 	if options.Entries != "" {
 		args = append(args, "--entries", options.Entries)
@@ -229,6 +911,13 @@ func (t *Tart) Prune(options PruneOptions) error {
 	if options.SpaceBudget > 0 {
 		args = append(args, "--space-budget", fmt.Sprintf("%d", options.SpaceBudget))
 	}
+	return args
+}
+
+// Prune prunes OCI and IPSW caches or local VMs.
+// It returns an error if the pruning process fails.
+func (t *Tart) Prune(options PruneOptions) error {
+	args := pruneArgs(options)
 	output, err := t.run(args...)
 	if err != nil {
 		return fmt.Errorf("failed to prune: %w, output: %s", err, string(output))
@@ -0,0 +1,628 @@
+package tart
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCloneRandomizeMAC(t *testing.T) {
+	tests := []struct {
+		name    string
+		options CloneOptions
+		want    [][]string
+		wantErr bool
+	}{
+		{
+			name:    "keep source address",
+			options: CloneOptions{},
+			want:    [][]string{{"clone", "base", "vm"}},
+		},
+		{
+			name:    "randomize",
+			options: CloneOptions{RandomizeMAC: true},
+			want:    [][]string{{"clone", "base", "vm"}, {"set", "vm", "--random-mac"}},
+		},
+		{
+			name:    "with state",
+			options: CloneOptions{RandomizeMAC: true, IncludeState: true},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeTart(t, "")
+			err := f.Clone("base", "vm", tt.options)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Clone error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var got [][]string
+			for _, call := range f.calls(t) {
+				if call[0] != "list" {
+					got = append(got, call)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("calls = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExportArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		options ExportOptions
+		want    []string
+		wantErr bool
+	}{
+		{name: "default", path: "vm.tvm", want: []string{"export", "vm", "vm.tvm"}},
+		{name: "default path", want: []string{"export", "vm"}},
+		{name: "none", path: "vm.tvm", options: ExportOptions{Compression: CompressionNone}, want: []string{"export", "vm", "vm.tvm", "--compression", "none"}},
+		{name: "lz4", path: "vm.tvm", options: ExportOptions{Compression: CompressionLZ4}, want: []string{"export", "vm", "vm.tvm", "--compression", "lz4"}},
+		{name: "zstd", path: "vm.tvm", options: ExportOptions{Compression: CompressionZstd}, want: []string{"export", "vm", "vm.tvm", "--compression", "zstd"}},
+		{name: "invalid", path: "vm.tvm", options: ExportOptions{Compression: "gzip"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := exportArgs("vm", tt.path, tt.options)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("exportArgs error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("exportArgs = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExportCompressionNeedsFlag(t *testing.T) {
+	tests := []struct {
+		name        string
+		help        string
+		wantErr     error
+		wantExports int
+	}{
+		{name: "unsupported", help: "--help   Show help", wantErr: ErrUnsupported},
+		{name: "supported", help: "--compression <compression>   Compression to use", wantExports: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeTart(t, `
+case "$2" in
+--help) echo "`+tt.help+`"; exit 0;;
+esac
+case "$1" in
+export) touch "$3"; exit 0;;
+esac`)
+			path := filepath.Join(t.TempDir(), "vm.tvm")
+			err := f.ExportWithOptions(context.Background(), "vm", path, ExportOptions{Compression: CompressionZstd})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ExportWithOptions = %v, want %v", err, tt.wantErr)
+			}
+			var exports int
+			for _, call := range f.commandCalls(t, "export") {
+				if call[1] != "--help" {
+					exports++
+				}
+			}
+			if exports != tt.wantExports {
+				t.Errorf("got %d exports, want %d", exports, tt.wantExports)
+			}
+		})
+	}
+}
+
+func TestSetConfigAtomic(t *testing.T) {
+	const original = `{"cpuCount":2,"macAddress":"7e:00:00:00:00:01","memorySize":4294967296}`
+	tests := []struct {
+		name       string
+		state      string
+		setFails   bool
+		wantErr    bool
+		wantSets   int
+		wantConfig string
+	}{
+		{name: "applied", state: "stopped", wantSets: 1},
+		{name: "rolled back", state: "stopped", setFails: true, wantErr: true, wantSets: 1, wantConfig: original},
+		{name: "running", state: "running", wantErr: true, wantConfig: original},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := ""
+			if tt.setFails {
+				script = `[ "$1" = set ] && { echo "failed to set memory" >&2; exit 1; }`
+			}
+			f := newFakeTart(t, script)
+			f.setList(t, `[{"name":"vm","state":"`+tt.state+`"}]`)
+			f.addVM(t, "vm", original)
+			err := f.SetConfigAtomic("vm", VMConfig{CPUCount: 4, MACAddress: "7e:00:00:00:00:02"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetConfigAtomic error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if sets := f.commandCalls(t, "set"); len(sets) != tt.wantSets {
+				t.Errorf("got %d set calls, want %d", len(sets), tt.wantSets)
+			}
+			data, err := os.ReadFile(filepath.Join(f.vmDir("vm"), "config.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantConfig != "" && string(data) != tt.wantConfig {
+				t.Errorf("config.json = %s, want %s", data, tt.wantConfig)
+			}
+			if !tt.wantErr && !strings.Contains(string(data), "7e:00:00:00:00:02") {
+				t.Errorf("config.json = %s, want the new MAC address", data)
+			}
+		})
+	}
+}
+
+// emptyDir reports whether a directory has no entries.
+func emptyDir(t *testing.T, dir string) bool {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(entries) == 0
+}
+
+func TestExportTo(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		want    string
+		wantErr bool
+	}{
+		{name: "streams the archive", script: `[ "$1" = export ] && { printf 'archive of %s' "$2" > "$3"; exit 0; }`, want: "archive of vm"},
+		{name: "export fails", script: `[ "$1" = export ] && { echo "disk busy" >&2; exit 1; }`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeTart(t, tt.script)
+			tmp := t.TempDir()
+			t.Setenv("TMPDIR", tmp)
+			var out bytes.Buffer
+			err := f.ExportTo("vm", &out)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExportTo error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if out.String() != tt.want {
+				t.Errorf("streamed %q, want %q", out.String(), tt.want)
+			}
+			if !emptyDir(t, tmp) {
+				t.Error("the staged export was left behind")
+			}
+		})
+	}
+}
+
+func TestImportFrom(t *testing.T) {
+	tests := []struct {
+		name        string
+		list        string
+		wantErr     error
+		wantImports int
+	}{
+		{name: "imports the stream", list: `[]`, wantImports: 1},
+		{name: "name taken", list: `[{"name":"vm","state":"stopped"}]`, wantErr: ErrVMAlreadyExists},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeTart(t, `[ "$1" = import ] && { cp "$2" "$FAKE_TART_DIR/imported"; exit 0; }`)
+			f.setList(t, tt.list)
+			tmp := t.TempDir()
+			t.Setenv("TMPDIR", tmp)
+			err := f.ImportFrom(strings.NewReader("archive bytes"), "vm")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ImportFrom = %v, want %v", err, tt.wantErr)
+			}
+			imports := f.commandCalls(t, "import")
+			if len(imports) != tt.wantImports {
+				t.Fatalf("got %d imports, want %d", len(imports), tt.wantImports)
+			}
+			if tt.wantImports > 0 {
+				if imports[0][2] != "vm" {
+					t.Errorf("imported as %q, want vm", imports[0][2])
+				}
+				data, err := os.ReadFile(filepath.Join(f.dir, "imported"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(data) != "archive bytes" {
+					t.Errorf("tart imported %q, want the stream's contents", data)
+				}
+			}
+			if !emptyDir(t, tmp) {
+				t.Error("the staged archive was left behind")
+			}
+		})
+	}
+}
+
+func TestCloneMetadata(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  map[string]string
+		options CloneOptions
+		want    map[string]string
+	}{
+		{name: "not copied", source: map[string]string{"owner": "ci"}, options: CloneOptions{}, want: map[string]string{}},
+		{
+			name:    "copied",
+			source:  map[string]string{"owner": "ci", "pool": "a"},
+			options: CloneOptions{CopyMetadata: true},
+			want:    map[string]string{"owner": "ci", "pool": "a"},
+		},
+		{
+			name:    "merged over copy",
+			source:  map[string]string{"owner": "ci", "pool": "a"},
+			options: CloneOptions{CopyMetadata: true, Metadata: map[string]string{"pool": "b"}},
+			want:    map[string]string{"owner": "ci", "pool": "b"},
+		},
+		{
+			name:    "source without metadata",
+			options: CloneOptions{CopyMetadata: true, Metadata: map[string]string{"pool": "b"}},
+			want:    map[string]string{"pool": "b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeTart(t, "")
+			if tt.source != nil {
+				if err := f.metadata().Set("base", tt.source); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := f.Clone("base", "vm", tt.options); err != nil {
+				t.Fatalf("Clone: %v", err)
+			}
+			got, err := f.GetMetadata("vm")
+			if err != nil {
+				t.Fatalf("GetMetadata: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("clone metadata = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateChecksIPSW(t *testing.T) {
+	workDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workDir, "restore.ipsw"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		ipsw    string
+		wantErr bool
+	}{
+		{name: "latest", ipsw: "latest"},
+		{name: "url", ipsw: "https://updates.cdn-apple.com/restore.ipsw"},
+		{name: "relative to WorkDir", ipsw: "restore.ipsw"},
+		{name: "absolute", ipsw: filepath.Join(workDir, "restore.ipsw")},
+		{name: "missing", ipsw: "typo.ipsw", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeTart(t, "")
+			f.WorkDir = workDir
+			err := f.Create("vm", CreateOptions{FromIPSW: tt.ipsw})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Create error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), tt.ipsw) {
+				t.Errorf("Create error = %v, want it to name %s", err, tt.ipsw)
+			}
+			want := 1
+			if tt.wantErr {
+				want = 0
+			}
+			if creates := f.commandCalls(t, "create"); len(creates) != want {
+				t.Errorf("create calls = %q, want %d", creates, want)
+			}
+		})
+	}
+}
+
+func TestCloneIncludeState(t *testing.T) {
+	tests := []struct {
+		name      string
+		state     string
+		carried   bool
+		want      string
+		wantErr   bool
+		wantClone bool
+	}{
+		{name: "suspended", state: "suspended", want: "saved", wantClone: true},
+		{name: "carried over by tart", state: "suspended", carried: true, want: "tart's", wantClone: true},
+		{name: "running", state: "running", wantErr: true},
+		{name: "stopped", state: "stopped", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := `
+if [ "$1" = clone ]; then
+	mkdir -p "$TART_HOME/vms/$3"
+	[ -n "$CARRIED" ] && printf "tart's" > "$TART_HOME/vms/$3/state.vzvmsave"
+	exit 0
+fi`
+			f := newFakeTart(t, script)
+			if tt.carried {
+				t.Setenv("CARRIED", "1")
+			}
+			f.setList(t, `[{"name":"base","state":"`+tt.state+`"}]`)
+			f.addVM(t, "base", `{}`)
+			if err := os.WriteFile(filepath.Join(f.vmDir("base"), suspendStateFile), []byte("saved"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			err := f.Clone("base", "vm", CloneOptions{IncludeState: true})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Clone error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if clones := f.commandCalls(t, "clone"); (len(clones) == 1) != tt.wantClone {
+				t.Errorf("clone calls = %q, want a clone %v", clones, tt.wantClone)
+			}
+			if tt.wantErr {
+				return
+			}
+			got, err := os.ReadFile(filepath.Join(f.vmDir("vm"), suspendStateFile))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("clone state = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSkipPrecheck(t *testing.T) {
+	ops := []struct {
+		name    string
+		command string
+		op      func(f *fakeTart) error
+	}{
+		{name: "create", command: "create", op: func(f *fakeTart) error { return f.Create("vm", CreateOptions{Linux: true}) }},
+		{name: "clone", command: "clone", op: func(f *fakeTart) error { return f.Clone("base", "vm", CloneOptions{}) }},
+		{name: "import", command: "import", op: func(f *fakeTart) error { return f.Import("vm.tvm", "vm") }},
+	}
+	for _, op := range ops {
+		for _, skip := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s skip=%v", op.name, skip), func(t *testing.T) {
+				f := newFakeTart(t, `
+case "$1" in
+create|clone|import) echo 'VM "vm" already exists' >&2; exit 1;;
+esac`)
+				f.SkipPrecheck = skip
+				f.setList(t, `[{"name":"vm","state":"stopped"}]`)
+				err := op.op(f)
+				if !errors.Is(err, ErrVMAlreadyExists) {
+					t.Errorf("%s = %v, want ErrVMAlreadyExists", op.name, err)
+				}
+				lists, runs := len(f.commandCalls(t, "list")), len(f.commandCalls(t, op.command))
+				if skip && (lists != 0 || runs != 1) || !skip && (lists != 1 || runs != 0) {
+					t.Errorf("list calls = %d, %s calls = %d", lists, op.command, runs)
+				}
+			})
+		}
+	}
+}
+
+func TestRename(t *testing.T) {
+	tests := []struct {
+		name    string
+		list    string
+		wantErr error
+	}{
+		{name: "renamed", list: `[{"name":"old","state":"stopped"}]`},
+		{name: "missing", list: `[]`, wantErr: ErrVMNotFound},
+		{name: "taken", list: `[{"name":"old","state":"stopped"},{"name":"new","state":"stopped"}]`, wantErr: ErrVMAlreadyExists},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeTart(t, `
+if [ "$1" = rename ]; then
+	echo "[{\"name\":\"$3\",\"state\":\"stopped\"}]" > "$FAKE_TART_DIR/list.json"
+	exit 0
+fi`)
+			f.setList(t, tt.list)
+			if err := f.metadata().Set("old", map[string]string{"owner": "ci"}); err != nil {
+				t.Fatal(err)
+			}
+			s, err := f.Rename("old", "new")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Rename = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if renames := f.commandCalls(t, "rename"); len(renames) != 0 {
+					t.Errorf("rename calls = %q, want none", renames)
+				}
+				return
+			}
+			if s.Name != "new" {
+				t.Errorf("Rename state = %+v, want the renamed VM", s)
+			}
+			moved, err := f.GetMetadata("new")
+			if err != nil {
+				t.Fatal(err)
+			}
+			stale, err := f.metadata().Get("old")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if moved["owner"] != "ci" || len(stale) != 0 {
+				t.Errorf("metadata after rename: new = %v, old = %v", moved, stale)
+			}
+		})
+	}
+}
+
+func TestStop(t *testing.T) {
+	tests := []struct {
+		name          string
+		list          string
+		wantStop      error
+		wantIfRunning error
+		wantStops     int
+	}{
+		{name: "running", list: `[{"name":"vm","state":"running"}]`, wantStops: 2},
+		{name: "stopped", list: `[{"name":"vm","state":"stopped"}]`, wantStop: ErrVMNotRunning},
+		{name: "missing", list: `[]`, wantStop: ErrVMNotFound, wantIfRunning: ErrVMNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeTart(t, "")
+			f.setList(t, tt.list)
+			if err := f.Stop("vm", 0); !errors.Is(err, tt.wantStop) {
+				t.Errorf("Stop = %v, want %v", err, tt.wantStop)
+			}
+			if err := f.StopIfRunning("vm", 0); !errors.Is(err, tt.wantIfRunning) {
+				t.Errorf("StopIfRunning = %v, want %v", err, tt.wantIfRunning)
+			}
+			if stops := f.commandCalls(t, "stop"); len(stops) != tt.wantStops {
+				t.Errorf("stop calls = %q, want %d", stops, tt.wantStops)
+			}
+		})
+	}
+}
+
+func TestSuspendFile(t *testing.T) {
+	f := newFakeTart(t, "")
+	f.addVM(t, "suspended", `{}`)
+	f.addVM(t, "fresh", `{}`)
+	state := filepath.Join(f.vmDir("suspended"), suspendStateFile)
+	if err := os.WriteFile(state, make([]byte, 42), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		wantPath string
+		wantSize int64
+		wantErr  error
+	}{
+		{name: "suspended", wantPath: state, wantSize: 42},
+		{name: "fresh", wantErr: ErrNoSuspendState},
+		{name: "missing", wantErr: ErrVMNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := f.SuspendFile(tt.name)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SuspendFile = %v, want %v", err, tt.wantErr)
+			}
+			if got.Path != tt.wantPath || got.Size != tt.wantSize {
+				t.Errorf("SuspendFile = %+v, want %s with %d bytes", got, tt.wantPath, tt.wantSize)
+			}
+		})
+	}
+}
+
+func TestDiscardSuspendState(t *testing.T) {
+	tests := []struct {
+		name    string
+		state   string
+		wantErr bool
+	}{
+		{name: "suspended", state: "suspended"},
+		{name: "running", state: "running", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeTart(t, "")
+			f.setList(t, `[{"name":"vm","state":"`+tt.state+`"}]`)
+			f.addVM(t, "vm", `{}`)
+			state := filepath.Join(f.vmDir("vm"), suspendStateFile)
+			if err := os.WriteFile(state, nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+			err := f.DiscardSuspendState("vm")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DiscardSuspendState = %v, wantErr %v", err, tt.wantErr)
+			}
+			if _, err := os.Stat(state); (err == nil) != tt.wantErr {
+				t.Errorf("suspend state kept = %v, want %v", err == nil, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStopAndWait(t *testing.T) {
+	tests := []struct {
+		name     string
+		script   string
+		list     string
+		wantErr  error
+		wantStop bool
+	}{
+		{
+			name: "stops after a few polls",
+			script: `
+case "$1" in
+stop) touch "$FAKE_TART_DIR/stopping"; exit 0;;
+list)
+	if [ -f "$FAKE_TART_DIR/stopping" ]; then
+		n=$(cat "$FAKE_TART_DIR/polls" 2>/dev/null || echo 0)
+		echo $((n+1)) > "$FAKE_TART_DIR/polls"
+		if [ "$n" -ge 2 ]; then echo '[{"name":"vm","state":"stopped"}]'; exit 0; fi
+	fi;;
+esac`,
+			list:     `[{"name":"vm","state":"running"}]`,
+			wantStop: true,
+		},
+		{name: "already stopped", list: `[{"name":"vm","state":"stopped"}]`},
+		{name: "never stops", list: `[{"name":"vm","state":"running"}]`, wantErr: context.DeadlineExceeded, wantStop: true},
+		{name: "missing", list: `[]`, wantErr: ErrVMNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeTart(t, tt.script)
+			f.setList(t, tt.list)
+			f.PollBackoff = Backoff{Initial: 10 * time.Millisecond, Jitter: -1}
+			ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer cancel()
+			err := f.StopAndWait(ctx, "vm", 0)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("StopAndWait = %v, want %v", err, tt.wantErr)
+			}
+			var timeout *StopTimeoutError
+			if errors.As(err, &timeout) != (tt.wantErr == context.DeadlineExceeded) {
+				t.Errorf("StopAndWait = %v, want a *StopTimeoutError only on timeout", err)
+			}
+			if stops := f.commandCalls(t, "stop"); (len(stops) == 1) != tt.wantStop {
+				t.Errorf("stop calls = %q, want a stop %v", stops, tt.wantStop)
+			}
+		})
+	}
+}
+
+func TestCreatedAt(t *testing.T) {
+	f := newFakeTart(t, "")
+	before := time.Now().Add(-time.Second)
+	f.addVM(t, "vm", `{}`)
+	after := time.Now().Add(time.Second)
+
+	got, err := f.CreatedAt("vm")
+	if err != nil {
+		t.Fatalf("CreatedAt: %v", err)
+	}
+	if got.Before(before) || got.After(after) {
+		t.Errorf("CreatedAt = %v, want between %v and %v", got, before, after)
+	}
+	if _, err := f.CreatedAt("missing"); !errors.Is(err, ErrVMNotFound) {
+		t.Errorf("CreatedAt(missing) = %v, want ErrVMNotFound", err)
+	}
+	f.Transport = remoteTransport{}
+	if _, err := f.CreatedAt("vm"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("remote CreatedAt = %v, want ErrUnsupported", err)
+	}
+}
@@ -0,0 +1,178 @@
+package tart
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// dhcpLeasesPath is where macOS's DHCP server, which hands out addresses on
+// Tart's shared network, records its leases.
+const dhcpLeasesPath = "/var/db/dhcpd_leases"
+
+// MACAddress retrieves a VM's MAC address in canonical form, i.e. six
+// lowercase, zero-padded hex octets separated by colons.
+// It returns ErrNoMACAddress if the VM has no MAC address yet, and an error if
+// the configuration can't be read or the address is malformed.
+func (t *Tart) MACAddress(name string) (string, error) {
+	config, err := t.Config(name)
+	if err != nil {
+		return "", fmt.Errorf("failed to get VM MAC address: %w", err)
+	}
+	if config.MACAddress == "" {
+		return "", ErrNoMACAddress
+	}
+	return canonicalMAC(config.MACAddress)
+}
+
+// canonicalMAC normalizes a MAC address such as "A:b:0C:d:e:F" to
+// "0a:0b:0c:0d:0e:0f". Leading zeros are commonly dropped by macOS tooling.
+func canonicalMAC(mac string) (string, error) {
+	parts := strings.Split(strings.TrimSpace(mac), ":")
+	if len(parts) != 6 {
+		return "", fmt.Errorf("invalid MAC address: %s", mac)
+	}
+	for i, p := range parts {
+		b, err := strconv.ParseUint(p, 16, 8)
+		if err != nil || p == "" {
+			return "", fmt.Errorf("invalid MAC address: %s", mac)
+		}
+		parts[i] = fmt.Sprintf("%02x", b)
+	}
+	return strings.Join(parts, ":"), nil
+}
+
+// DeterministicMAC derives a MAC address from a seed, so that recreating a
+// VM with the same seed gives it the same network identity. The address is
+// always a locally-administered unicast one, so it can't collide with
+// hardware vendors' addresses.
+func DeterministicMAC(seed string) string {
+	sum := sha256.Sum256([]byte(seed))
+	mac := net.HardwareAddr(sum[:6])
+	mac[0] = mac[0]&^0x01 | 0x02
+	return mac.String()
+}
+
+// checkUnicastMAC returns the canonical form of mac, or an error if it
+// isn't a valid unicast MAC address.
+func checkUnicastMAC(mac string) (string, error) {
+	canonical, err := canonicalMAC(mac)
+	if err != nil {
+		return "", err
+	}
+	if hw, _ := net.ParseMAC(canonical); hw[0]&0x01 != 0 {
+		return "", fmt.Errorf("invalid MAC address: %s is a multicast address", mac)
+	}
+	return canonical, nil
+}
+
+// ResolveIP retrieves a VM's IP address, falling back between resolution
+// methods. The host's DHCP leases are consulted first, as that's instant and
+// independent of Tart's resolver; if the VM's MAC address has no lease, Tart
+// is asked with the given wait.
+// It returns an error if neither method finds an address.
+func (t *Tart) ResolveIP(name string, wait int) (string, error) {
+	if ip, err := t.IPFromLeases(name); err == nil {
+		return ip, nil
+	}
+	return t.IP(name, wait, "")
+}
+
+// Leases reads the host's DHCP leases into a map of canonical MAC address to
+// IP address, which covers every VM on Tart's shared network at once.
+// It returns an error if the leases file doesn't exist (no VM has obtained a
+// lease yet), can't be read, or can't be parsed.
+func (t *Tart) Leases() (map[string]string, error) {
+	leases, err := readLeases(dhcpLeasesPath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("no DHCP leases file at %s: no VM has obtained a lease yet", dhcpLeasesPath)
+	case errors.Is(err, os.ErrPermission):
+		return nil, fmt.Errorf("permission denied reading DHCP leases at %s", dhcpLeasesPath)
+	case err != nil:
+		return nil, fmt.Errorf("failed to read DHCP leases: %w", err)
+	}
+	return leases, nil
+}
+
+// IPFromLeases retrieves a VM's IP address by looking up its MAC address in
+// the host's DHCP leases, without involving Tart's resolver.
+// It returns an error wrapping ErrIPNotReady if the VM has no lease, or an
+// error if the MAC address or leases can't be read.
+func (t *Tart) IPFromLeases(name string) (string, error) {
+	mac, err := t.MACAddress(name)
+	if err != nil {
+		return "", err
+	}
+	leases, err := t.Leases()
+	if err != nil {
+		return "", err
+	}
+	ip, ok := leases[mac]
+	if !ok {
+		return "", fmt.Errorf("%w: %s has no DHCP lease", ErrIPNotReady, name)
+	}
+	return ip, nil
+}
+
+// readLeases parses a macOS dhcpd_leases file into a map of canonical MAC
+// address to IP address. Entries look like:
+//
+//	{
+//		name=admins-Virtual-Machine
+//		ip_address=192.168.64.3
+//		hw_address=1,a6:2:4e:13:1e:5f
+//		identifier=1,a6:2:4e:13:1e:5f
+//		lease=0x66b0f7a5
+//	}
+//
+// The most recent lease comes first, so earlier entries win.
+func readLeases(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseLeases(f)
+}
+
+// parseLeases parses dhcpd_leases content; see readLeases.
+func parseLeases(r io.Reader) (map[string]string, error) {
+	leases := map[string]string{}
+	var ip, mac string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "{":
+			ip, mac = "", ""
+		case line == "}":
+			if ip != "" && mac != "" {
+				if _, seen := leases[mac]; !seen {
+					leases[mac] = ip
+				}
+			}
+		case strings.HasPrefix(line, "ip_address="):
+			ip = strings.TrimPrefix(line, "ip_address=")
+		case strings.HasPrefix(line, "hw_address="):
+			// The value is prefixed with the hardware type, "1" for Ethernet
+			_, hw, ok := strings.Cut(strings.TrimPrefix(line, "hw_address="), ",")
+			if !ok {
+				continue
+			}
+			if canonical, err := canonicalMAC(hw); err == nil {
+				mac = canonical
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read DHCP leases: %w", err)
+	}
+	return leases, nil
+}
@@ -0,0 +1,145 @@
+package tart
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDeterministicMAC(t *testing.T) {
+	a := DeterministicMAC("build-42")
+	if b := DeterministicMAC("build-42"); a != b {
+		t.Errorf("same seed gave %s and %s", a, b)
+	}
+	if c := DeterministicMAC("build-43"); a == c {
+		t.Errorf("different seeds gave the same MAC %s", a)
+	}
+	for _, seed := range []string{"", "a", "build-42", "another seed"} {
+		hw, err := net.ParseMAC(DeterministicMAC(seed))
+		if err != nil {
+			t.Fatalf("seed %q: %v", seed, err)
+		}
+		if hw[0]&0x01 != 0 {
+			t.Errorf("seed %q: %s is multicast", seed, hw)
+		}
+		if hw[0]&0x02 == 0 {
+			t.Errorf("seed %q: %s is not locally administered", seed, hw)
+		}
+	}
+}
+
+func TestCheckUnicastMAC(t *testing.T) {
+	tests := []struct {
+		mac     string
+		want    string
+		wantErr bool
+	}{
+		{mac: "0A:0:0:0:0:1", want: "0a:00:00:00:00:01"},
+		{mac: "01:00:5e:00:00:01", wantErr: true},
+		{mac: "0a:00:00:00:00", wantErr: true},
+		{mac: "zz:00:00:00:00:01", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := checkUnicastMAC(tt.mac)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("checkUnicastMAC(%q) = %q, %v", tt.mac, got, err)
+		}
+	}
+}
+
+func TestMACAddress(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		want    string
+		wantErr bool
+		is      error
+	}{
+		{name: "canonical", config: `{"macAddress":"7e:00:00:0a:0b:0c"}`, want: "7e:00:00:0a:0b:0c"},
+		{name: "dropped zeros and uppercase", config: `{"macAddress":"7E:0:0:A:b:C"}`, want: "7e:00:00:0a:0b:0c"},
+		{name: "none yet", config: `{}`, wantErr: true, is: ErrNoMACAddress},
+		{name: "malformed", config: `{"macAddress":"7e:00:00:0a:0b"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeTart(t, "")
+			f.addVM(t, "vm", tt.config)
+			got, err := f.MACAddress("vm")
+			if (err != nil) != tt.wantErr || (tt.is != nil && !errors.Is(err, tt.is)) {
+				t.Fatalf("MACAddress error = %v, wantErr %v (%v)", err, tt.wantErr, tt.is)
+			}
+			if got != tt.want {
+				t.Errorf("MACAddress = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveIPFallsBackToTart(t *testing.T) {
+	if _, err := os.Stat(dhcpLeasesPath); err == nil {
+		t.Skip("the host has DHCP leases that could answer first")
+	}
+	f := newFakeTart(t, `
+if [ "$1" = ip ]; then echo 192.168.64.9; exit 0; fi`)
+	f.addVM(t, "vm", `{"macAddress":"7e:00:00:0a:0b:0c"}`)
+	ip, err := f.ResolveIP("vm", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ip != "192.168.64.9" {
+		t.Errorf("ResolveIP = %q, want tart's answer", ip)
+	}
+	if calls := f.commandCalls(t, "ip"); len(calls) != 1 || !reflect.DeepEqual(calls[0], []string{"ip", "vm", "--wait", "10"}) {
+		t.Errorf("ip calls = %q", calls)
+	}
+}
+
+func TestParseLeases(t *testing.T) {
+	leases := `{
+	name=newest
+	ip_address=192.168.64.5
+	hw_address=1,7e:0:0:a:b:c
+	identifier=1,7e:0:0:a:b:c
+	lease=0x66b0f7a5
+}
+{
+	name=older
+	ip_address=192.168.64.3
+	hw_address=1,7e:0:0:a:b:c
+	lease=0x66b0f000
+}
+{
+	name=other
+	ip_address=192.168.64.4
+	hw_address=1,A6:2:4E:13:1E:5F
+	lease=0x66b0f000
+}
+{
+	name=malformed
+	ip_address=192.168.64.6
+	hw_address=a6:2:4e
+}
+`
+	got, err := parseLeases(strings.NewReader(leases))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"7e:00:00:0a:0b:0c": "192.168.64.5",
+		"a6:02:4e:13:1e:5f": "192.168.64.4",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseLeases = %v, want %v", got, want)
+	}
+}
+
+func TestReadLeasesMissing(t *testing.T) {
+	_, err := readLeases(filepath.Join(t.TempDir(), "dhcpd_leases"))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("readLeases = %v, want ErrNotExist", err)
+	}
+}
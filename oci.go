@@ -0,0 +1,192 @@
+package tart
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// manifestMediaTypes are the manifest formats accepted from registries.
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// imageReference represents a parsed OCI image reference.
+type imageReference struct {
+	Host       string
+	Repository string
+	// Reference is a tag or a digest.
+	Reference string
+}
+
+// parseReference parses an OCI image reference such as
+// "ghcr.io/cirruslabs/macos-sonoma-base:latest". A missing tag means "latest".
+func parseReference(ref string) (imageReference, error) {
+	var r imageReference
+	slash := strings.Index(ref, "/")
+	if slash <= 0 {
+		return r, fmt.Errorf("invalid image reference %q: missing registry host", ref)
+	}
+	r.Host, r.Repository, r.Reference = ref[:slash], ref[slash+1:], "latest"
+	if i := strings.LastIndex(r.Repository, "@"); i >= 0 {
+		r.Repository, r.Reference = r.Repository[:i], r.Repository[i+1:]
+	} else if i := strings.LastIndex(r.Repository, ":"); i > strings.LastIndex(r.Repository, "/") {
+		r.Repository, r.Reference = r.Repository[:i], r.Repository[i+1:]
+	}
+	if r.Repository == "" || r.Reference == "" {
+		return r, fmt.Errorf("invalid image reference %q", ref)
+	}
+	return r, nil
+}
+
+// registryClient talks to an OCI registry's HTTP API.
+type registryClient struct {
+	http        *http.Client
+	insecure    bool
+	credentials *Credentials
+	token       string
+}
+
+// url returns the URL of a registry API path for the reference's registry.
+func (c *registryClient) url(r imageReference, path string) string {
+	scheme := "https"
+	if c.insecure {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s/v2/%s/%s", scheme, r.Host, r.Repository, path)
+}
+
+// do sends a request, authenticating with a bearer token if the registry
+// asks for one.
+func (c *registryClient) do(ctx context.Context, method string, url string, accept []string) (*http.Response, error) {
+	send := func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, method, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range accept {
+			req.Header.Add("Accept", a)
+		}
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+		return c.http.Do(req)
+	}
+	resp, err := send()
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusUnauthorized || c.token != "" {
+		return resp, nil
+	}
+	resp.Body.Close()
+	if err := c.authenticate(ctx, resp.Header.Get("WWW-Authenticate")); err != nil {
+		return nil, err
+	}
+	return send()
+}
+
+// parseChallengeParams parses the comma-separated key=value parameters of a
+// WWW-Authenticate challenge. Values may be quoted, in which case they can
+// contain commas, such as a scope of "repository:x:pull,push", and
+// backslash-escaped characters.
+func parseChallengeParams(s string) map[string]string {
+	params := map[string]string{}
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		key, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			return params
+		}
+		key = strings.TrimSpace(key)
+		rest = strings.TrimLeft(rest, " \t")
+		var value strings.Builder
+		if strings.HasPrefix(rest, `"`) {
+			i := 1
+			for ; i < len(rest) && rest[i] != '"'; i++ {
+				if rest[i] == '\\' && i+1 < len(rest) {
+					i++
+				}
+				value.WriteByte(rest[i])
+			}
+			s = rest[min(i+1, len(rest)):]
+		} else {
+			end := strings.IndexByte(rest, ',')
+			if end < 0 {
+				end = len(rest)
+			}
+			value.WriteString(strings.TrimSpace(rest[:end]))
+			s = rest[end:]
+		}
+		params[key] = value.String()
+	}
+}
+
+// authenticate obtains a bearer token as described by a WWW-Authenticate
+// challenge.
+func (c *registryClient) authenticate(ctx context.Context, challenge string) error {
+	if !strings.HasPrefix(challenge, "Bearer ") {
+		return fmt.Errorf("unsupported registry authentication challenge: %q", challenge)
+	}
+	params := parseChallengeParams(strings.TrimPrefix(challenge, "Bearer "))
+	realm := params["realm"]
+	if realm == "" {
+		return errors.New("registry authentication challenge has no realm")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm, nil)
+	if err != nil {
+		return err
+	}
+	q := req.URL.Query()
+	if params["service"] != "" {
+		q.Set("service", params["service"])
+	}
+	if params["scope"] != "" {
+		q.Set("scope", params["scope"])
+	}
+	req.URL.RawQuery = q.Encode()
+	if c.credentials != nil {
+		req.SetBasicAuth(c.credentials.Username, c.credentials.Password)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to get registry token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get registry token: %s", resp.Status)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return fmt.Errorf("failed to parse registry token: %w", err)
+	}
+	c.token = token.Token
+	if c.token == "" {
+		c.token = token.AccessToken
+	}
+	return nil
+}
+
+// digest returns the manifest digest the registry reports for a reference.
+func (c *registryClient) digest(ctx context.Context, r imageReference) (string, error) {
+	resp, err := c.do(ctx, http.MethodHead, c.url(r, "manifests/"+r.Reference), manifestMediaTypes)
+	if err != nil {
+		return "", fmt.Errorf("failed to query manifest: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to query manifest: %s", resp.Status)
+	}
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", errors.New("registry did not report a manifest digest")
+	}
+	return digest, nil
+}
@@ -0,0 +1,84 @@
+package tart
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseChallengeParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		params string
+		want   map[string]string
+	}{
+		{
+			name:   "quoted",
+			params: `realm="https://auth.example.com/token",service="registry.example.com"`,
+			want:   map[string]string{"realm": "https://auth.example.com/token", "service": "registry.example.com"},
+		},
+		{
+			name:   "comma inside quotes",
+			params: `realm="https://ghcr.io/token",scope="repository:org/image:pull,push",service="ghcr.io"`,
+			want:   map[string]string{"realm": "https://ghcr.io/token", "scope": "repository:org/image:pull,push", "service": "ghcr.io"},
+		},
+		{
+			name:   "unquoted with spaces",
+			params: `realm=https://auth.example.com/token, service = registry`,
+			want:   map[string]string{"realm": "https://auth.example.com/token", "service": "registry"},
+		},
+		{
+			name:   "escaped quote",
+			params: `realm="https://a/token",error="say \"hi\""`,
+			want:   map[string]string{"realm": "https://a/token", "error": `say "hi"`},
+		},
+		{
+			name:   "unterminated quote",
+			params: `realm="https://a/token`,
+			want:   map[string]string{"realm": "https://a/token"},
+		},
+		{name: "empty", params: "", want: map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseChallengeParams(tt.params); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseChallengeParams = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistryClientSendsQuotedScope(t *testing.T) {
+	const scope = "repository:org/image:pull,push"
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			if got := r.URL.Query().Get("scope"); got != scope {
+				t.Errorf("token scope = %q, want %q", got, scope)
+			}
+			w.Write([]byte(`{"token":"secret"}`))
+		case r.Header.Get("Authorization") == "Bearer secret":
+			w.Header().Set("Docker-Content-Digest", "sha256:abc")
+		default:
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+srv.URL+`/token",scope="`+scope+`",service="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+	c := &registryClient{http: srv.Client(), insecure: true}
+	r, err := parseReference(strings.TrimPrefix(srv.URL, "http://") + "/org/image")
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest, err := c.digest(context.Background(), r)
+	if err != nil {
+		t.Fatalf("digest: %v", err)
+	}
+	if digest != "sha256:abc" {
+		t.Errorf("digest = %q, want sha256:abc", digest)
+	}
+}
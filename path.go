@@ -0,0 +1,39 @@
+package tart
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Path returns the directory holding a VM's bundle.
+// Local VMs live in <ConfigDir>/vms/<name>, while pulled OCI images live in
+// <ConfigDir>/cache/OCIs/<host>/<repository>/<tag or digest>. Both layouts
+// are tried so that the lookup works for either kind of name.
+// It returns ErrVMNotFound if no bundle exists for the name.
+func (t *Tart) Path(name string) (string, error) {
+	for _, path := range t.bundleCandidates(name) {
+		info, err := os.Stat(path)
+		if err == nil && info.IsDir() {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrVMNotFound, name)
+}
+
+// bundleCandidates returns the locations where a VM's bundle may live.
+func (t *Tart) bundleCandidates(name string) []string {
+	candidates := []string{t.vmDir(name)}
+	if !strings.Contains(name, "/") {
+		return candidates
+	}
+	repository, reference := name, "latest"
+	if i := strings.LastIndex(name, "@"); i >= 0 {
+		repository, reference = name[:i], name[i+1:]
+	} else if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		repository, reference = name[:i], name[i+1:]
+	}
+	oci := filepath.Join(t.EffectiveConfigDir(), "cache", "OCIs", filepath.FromSlash(repository), reference)
+	return append(candidates, oci)
+}
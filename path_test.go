@@ -0,0 +1,46 @@
+package tart
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPath(t *testing.T) {
+	f := newFakeTart(t, "")
+	f.addVM(t, "vm", `{}`)
+	oci := filepath.Join(f.ConfigDir, "cache", "OCIs", "ghcr.io", "cirruslabs", "macos")
+	for _, ref := range []string{"latest", "sonoma", "sha256:abc"} {
+		if err := os.MkdirAll(filepath.Join(oci, ref), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(f.ConfigDir, "vms", "file"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		want    string
+		wantErr error
+	}{
+		{name: "vm", want: f.vmDir("vm")},
+		{name: "ghcr.io/cirruslabs/macos", want: filepath.Join(oci, "latest")},
+		{name: "ghcr.io/cirruslabs/macos:sonoma", want: filepath.Join(oci, "sonoma")},
+		{name: "ghcr.io/cirruslabs/macos@sha256:abc", want: filepath.Join(oci, "sha256:abc")},
+		{name: "ghcr.io/cirruslabs/macos:ventura", wantErr: ErrVMNotFound},
+		{name: "missing", wantErr: ErrVMNotFound},
+		{name: "file", wantErr: ErrVMNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := f.Path(tt.name)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Path error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Path = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
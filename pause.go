@@ -0,0 +1,64 @@
+package tart
+
+import "fmt"
+
+// Unlike Suspend, which saves a VM's memory to disk and ends its process,
+// pausing freezes the VM's vCPUs while it stays in memory. Tart releases to
+// date have no pause support, so Pause and Unpause check for `tart pause`
+// and `tart resume` commands first and return an error wrapping
+// ErrUnsupported when they're missing.
+
+// Pause freezes a running VM's vCPUs without saving its state to disk.
+// It returns an error wrapping ErrUnsupported if the installed Tart can't
+// pause VMs, one wrapping ErrVMNotFound or ErrVMNotRunning if the VM doesn't
+// exist or isn't running, or an error if the pause process fails.
+func (t *Tart) Pause(name string) error {
+	if err := t.requireCommand("pause"); err != nil {
+		return err
+	}
+	if err := t.requireState(name, StateRunning); err != nil {
+		return err
+	}
+	output, err := t.run("pause", name)
+	if err != nil {
+		return fmt.Errorf("failed to pause VM: %w, output: %s", err, string(output))
+	}
+	return nil
+}
+
+// Unpause resumes a VM paused with Pause.
+// It returns an error wrapping ErrUnsupported if the installed Tart can't
+// pause VMs, one wrapping ErrVMNotFound if the VM doesn't exist, or an error
+// if the VM isn't paused or the resume process fails.
+func (t *Tart) Unpause(name string) error {
+	if err := t.requireCommand("resume"); err != nil {
+		return err
+	}
+	if err := t.requireState(name, StatePaused); err != nil {
+		return err
+	}
+	output, err := t.run("resume", name)
+	if err != nil {
+		return fmt.Errorf("failed to unpause VM: %w, output: %s", err, string(output))
+	}
+	return nil
+}
+
+// requireState returns an error if a VM doesn't exist or isn't in the given
+// state.
+func (t *Tart) requireState(name string, state string) error {
+	s, err := t.State(name)
+	if err != nil {
+		return fmt.Errorf("failed to get VM state: %w", err)
+	}
+	if s.Name != name {
+		return fmt.Errorf("%w: %s", ErrVMNotFound, name)
+	}
+	if s.State == state {
+		return nil
+	}
+	if state == StateRunning {
+		return fmt.Errorf("%w: %s", ErrVMNotRunning, name)
+	}
+	return fmt.Errorf("VM %s is %s, not %s", name, s.State, state)
+}
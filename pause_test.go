@@ -0,0 +1,57 @@
+package tart
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPauseUnsupported(t *testing.T) {
+	f := newFakeTart(t, helpScript(`  run       Run a VM\n  suspend   Suspend a VM`))
+	f.setList(t, `[{"name":"vm","state":"running"}]`)
+	if err := f.Pause("vm"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Pause = %v, want ErrUnsupported", err)
+	}
+	if err := f.Unpause("vm"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Unpause = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestPause(t *testing.T) {
+	tests := []struct {
+		name       string
+		state      string
+		wantPause  error
+		wantResume bool
+	}{
+		{name: "running", state: "running"},
+		{name: "paused", state: "paused", wantPause: ErrVMNotRunning, wantResume: true},
+		{name: "missing", wantPause: ErrVMNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeTart(t, helpScript(`  pause     Pause a VM\n  resume    Resume a VM`))
+			if tt.state != "" {
+				f.setList(t, `[{"name":"vm","state":"`+tt.state+`"}]`)
+			}
+			if err := f.Pause("vm"); !errors.Is(err, tt.wantPause) {
+				t.Errorf("Pause = %v, want %v", err, tt.wantPause)
+			}
+			if err := f.Unpause("vm"); (err == nil) != tt.wantResume {
+				t.Errorf("Unpause = %v, want success %v", err, tt.wantResume)
+			}
+			wantPauses, wantResumes := 0, 0
+			if tt.wantPause == nil {
+				wantPauses = 1
+			}
+			if tt.wantResume {
+				wantResumes = 1
+			}
+			if n := len(f.commandCalls(t, "pause")); n != wantPauses {
+				t.Errorf("got %d pause calls, want %d", n, wantPauses)
+			}
+			if n := len(f.commandCalls(t, "resume")); n != wantResumes {
+				t.Errorf("got %d resume calls, want %d", n, wantResumes)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package tart
+
+// The Plan methods return the arguments the corresponding method would pass to
+// the tart command, without running it or performing any prechecks. They are
+// useful for logging intended actions before applying them, and for tests.
+
+// PlanRun returns the arguments Run would pass to tart.
+func (t *Tart) PlanRun(name string, options RunOptions) ([]string, error) {
+	return runArgs(name, options)
+}
+
+// PlanCreate returns the arguments Create would pass to tart.
+func (t *Tart) PlanCreate(name string, options CreateOptions) ([]string, error) {
+	return t.createArgs(name, options)
+}
+
+// PlanClone returns the arguments Clone would pass to tart.
+func (t *Tart) PlanClone(sourceName string, newName string, options CloneOptions) ([]string, error) {
+	return t.cloneArgs(sourceName, newName, options)
+}
+
+// PlanSetConfig returns the arguments SetConfig would pass to tart.
+func (t *Tart) PlanSetConfig(name string, config VMConfig) ([]string, error) {
+	return setConfigArgs(name, config), nil
+}
+
+// PlanExport returns the arguments Export would pass to tart.
+func (t *Tart) PlanExport(name string, path string) ([]string, error) {
+	return exportArgs(name, path, ExportOptions{})
+}
+
+// PlanStop returns the arguments Stop would pass to tart.
+func (t *Tart) PlanStop(name string, timeout int) ([]string, error) {
+	return stopArgs(name, timeout), nil
+}
+
+// PlanPrune returns the arguments Prune would pass to tart.
+func (t *Tart) PlanPrune(options PruneOptions) ([]string, error) {
+	return pruneArgs(options), nil
+}
+
+// PlanPush returns the arguments Push would pass to tart.
+func (t *Tart) PlanPush(name string, options PushOptions) ([]string, error) {
+	return t.pushArgs(name, options)
+}
+
+// PlanPull returns the arguments PullWithOptions would pass to tart.
+func (t *Tart) PlanPull(name string, options PullOptions) ([]string, error) {
+	return t.pullArgs(name, options)
+}
@@ -0,0 +1,137 @@
+package tart
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPlanCreate(t *testing.T) {
+	f := newFakeTart(t, "")
+	tests := []struct {
+		name    string
+		options CreateOptions
+		want    []string
+		wantErr bool
+	}{
+		{name: "linux", options: CreateOptions{Linux: true, DiskSize: 50}, want: []string{"create", "vm", "--linux", "--disk-size", "50"}},
+		{name: "ipsw", options: CreateOptions{FromIPSW: "latest"}, want: []string{"create", "vm", "--from-ipsw", "latest"}},
+		{name: "missing ipsw file", options: CreateOptions{FromIPSW: "/nonexistent/restore.ipsw"}, want: []string{"create", "vm", "--from-ipsw", "/nonexistent/restore.ipsw"}},
+		{name: "disk image", options: CreateOptions{FromDisk: "/nonexistent/disk.img"}, want: []string{"create", "vm", "--from-disk", "/nonexistent/disk.img"}},
+		{name: "two sources", options: CreateOptions{Linux: true, FromDisk: "disk.img"}, wantErr: true},
+		{name: "no source", options: CreateOptions{}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := f.PlanCreate("vm", tt.options)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PlanCreate error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PlanCreate = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if calls := f.calls(t); len(calls) != 0 {
+		t.Errorf("PlanCreate ran tart: %v", calls)
+	}
+}
+
+func TestCreateFromDiskUnsupported(t *testing.T) {
+	f := newFakeTart(t, "")
+	err := f.Create("vm", CreateOptions{FromDisk: "/nonexistent/disk.img"})
+	if err == nil {
+		t.Fatal("Create with a missing disk image succeeded")
+	}
+	if calls := f.calls(t); len(calls) != 0 {
+		t.Errorf("Create ran tart despite invalid options: %v", calls)
+	}
+}
+
+func TestCreateFromDiskNeedsFlag(t *testing.T) {
+	f := newFakeTart(t, "")
+	disk := filepath.Join(t.TempDir(), "disk.img")
+	if err := os.WriteFile(disk, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := f.Create("vm", CreateOptions{FromDisk: disk})
+	if !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Create = %v, want ErrUnsupported", err)
+	}
+	if creates := f.commandCalls(t, "create"); len(creates) != 1 || creates[0][1] != "--help" {
+		t.Errorf("create calls = %v, want only the help probe", creates)
+	}
+}
+
+func TestPlanMethods(t *testing.T) {
+	f := newFakeTart(t, "")
+	tests := []struct {
+		name    string
+		plan    func() ([]string, error)
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "run",
+			plan: func() ([]string, error) { return f.PlanRun("vm", RunOptions{NoGraphics: true}) },
+			want: []string{"run", "--no-graphics", "vm"},
+		},
+		{
+			name: "clone",
+			plan: func() ([]string, error) {
+				return f.PlanClone("base", "vm", CloneOptions{Insecure: true, Concurrency: 4, PruneLimit: 50})
+			},
+			want: []string{"clone", "base", "vm", "--insecure", "--concurrency", "4", "--prune-limit", "50"},
+		},
+		{
+			name:    "clone with negative prune limit",
+			plan:    func() ([]string, error) { return f.PlanClone("base", "vm", CloneOptions{PruneLimit: -1}) },
+			wantErr: true,
+		},
+		{
+			name: "set config",
+			plan: func() ([]string, error) {
+				config := VMConfig{CPUCount: 2, MemorySize: 4096}
+				config.Display.Width, config.Display.Height = 1024, 768
+				return f.PlanSetConfig("vm", config)
+			},
+			want: []string{"set", "vm", "--cpu", "2", "--memory", "4096", "--display", "1024x768"},
+		},
+		{
+			name: "set random mac",
+			plan: func() ([]string, error) { return f.PlanSetConfig("vm", VMConfig{MACAddress: "random"}) },
+			want: []string{"set", "vm", "--random-mac"},
+		},
+		{
+			name: "export",
+			plan: func() ([]string, error) { return f.PlanExport("vm", "/tmp/vm.tvm") },
+			want: []string{"export", "vm", "/tmp/vm.tvm"},
+		},
+		{
+			name: "export to default path",
+			plan: func() ([]string, error) { return f.PlanExport("vm", "") },
+			want: []string{"export", "vm"},
+		},
+		{
+			name: "stop",
+			plan: func() ([]string, error) { return f.PlanStop("vm", 30) },
+			want: []string{"stop", "vm", "--timeout", "30"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.plan()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if calls := f.calls(t); len(calls) != 0 {
+		t.Errorf("Plan methods ran tart: %v", calls)
+	}
+}
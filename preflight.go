@@ -0,0 +1,51 @@
+package tart
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// Preflight verifies that the environment can run Tart: the tart binary is
+// on the PATH and executable, it's at least MinimumVersion, the config
+// directory is writable, and the host is an Apple Silicon Mac.
+// It returns an error listing every problem found, or nil if there are none.
+func (t *Tart) Preflight() error {
+	var problems []error
+	if runtime.GOOS != "darwin" || runtime.GOARCH != "arm64" {
+		problems = append(problems, fmt.Errorf("unsupported host %s/%s: Tart requires darwin/arm64", runtime.GOOS, runtime.GOARCH))
+	}
+	configDirErr := checkWritable(t.EffectiveConfigDir())
+	// Tart commands don't start with an unusable ConfigDir, so the version is
+	// only checked if it passed; the directory problem is reported below
+	canRun := configDirErr == nil || t.ConfigDir == "" || t.remote()
+	if path, err := exec.LookPath("tart"); err != nil {
+		problems = append(problems, errors.New("tart command not found in PATH"))
+	} else if info, err := os.Stat(path); err != nil || info.Mode()&0111 == 0 {
+		problems = append(problems, fmt.Errorf("tart command at %s is not executable", path))
+	} else if canRun {
+		if version, err := t.Version(); err != nil {
+			problems = append(problems, err)
+		} else if compareVersions(version, MinimumVersion) < 0 {
+			problems = append(problems, fmt.Errorf("tart version %s is older than the minimum supported version %s", version, MinimumVersion))
+		}
+	}
+	if configDirErr != nil {
+		problems = append(problems, configDirErr)
+	}
+	return errors.Join(problems...)
+}
+
+// checkWritable probes whether files can be created in a config directory.
+// It returns a *ConfigDirError if they can't.
+func checkWritable(dir string) error {
+	f, err := os.CreateTemp(dir, ".go-tart-probe-")
+	if err != nil {
+		return &ConfigDirError{Path: dir, Err: err}
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return nil
+}
@@ -0,0 +1,84 @@
+package tart
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "2.0.0", b: "2.0.0", want: 0},
+		{a: "2.0", b: "2.0.0", want: 0},
+		{a: "v2.1.0", b: "2.0.9", want: 1},
+		{a: "2.10.0", b: "2.9.0", want: 1},
+		{a: "1.14.0", b: "2.0.0", want: -1},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestPreflight(t *testing.T) {
+	hostOK := runtime.GOOS == "darwin" && runtime.GOARCH == "arm64"
+	tests := []struct {
+		name       string
+		version    string
+		unwritable bool
+		want       []string
+	}{
+		{name: "ok", version: "2.18.0"},
+		{name: "old version", version: "1.14.0", want: []string{"older than the minimum supported version"}},
+		{name: "unwritable config dir", version: "2.18.0", unwritable: true, want: []string{"missing"}},
+		{name: "old version and unwritable config dir", version: "1.14.0", unwritable: true, want: []string{"missing"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeTart(t, `
+if [ "$1" = --version ]; then echo `+tt.version+`; exit 0; fi`)
+			if tt.unwritable {
+				f.ConfigDir = filepath.Join(f.ConfigDir, "missing")
+			}
+			want := tt.want
+			if !hostOK {
+				want = append([]string{"unsupported host"}, want...)
+			}
+			err := f.Preflight()
+			var problems []error
+			if err != nil {
+				problems = err.(interface{ Unwrap() []error }).Unwrap()
+			}
+			if len(problems) != len(want) {
+				t.Fatalf("Preflight = %v, want %d problems", err, len(want))
+			}
+			for i, problem := range problems {
+				if !strings.Contains(problem.Error(), want[i]) {
+					t.Errorf("problem %d = %v, want it to mention %q", i, problem, want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPreflightTartNotExecutable(t *testing.T) {
+	f := newFakeTart(t, "")
+	if err := os.Chmod(filepath.Join(f.dir, "tart"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := f.Preflight()
+	if err == nil || !strings.Contains(err.Error(), "tart command not found in PATH") && !strings.Contains(err.Error(), "not executable") {
+		t.Errorf("Preflight = %v, want a tart command problem", err)
+	}
+	var dirErr *ConfigDirError
+	if errors.As(err, &dirErr) {
+		t.Errorf("Preflight reported the config dir: %v", dirErr)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package tart
+
+import "errors"
+
+// setPriority is not supported on this platform.
+func setPriority(pid int, nice int) error {
+	return errors.New("process priority is not supported on this platform")
+}
@@ -0,0 +1,10 @@
+//go:build unix
+
+package tart
+
+import "syscall"
+
+// setPriority sets the scheduling priority (nice value) of a process.
+func setPriority(pid int, nice int) error {
+	return syscall.Setpriority(syscall.PRIO_PROCESS, pid, nice)
+}
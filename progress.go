@@ -0,0 +1,138 @@
+package tart
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// PullProgress is a progress update for a pull in flight.
+type PullProgress struct {
+	// Percent is how much of the disk has been pulled, from 0 to 100.
+	Percent float64 `json:"percent"`
+	// Bytes and TotalBytes are estimated from Percent and the compressed
+	// disk size Tart reports, so they're only as precise as that size.
+	Bytes      int64 `json:"bytes"`
+	TotalBytes int64 `json:"totalBytes"`
+	// Elapsed is the time since the pull started.
+	Elapsed time.Duration `json:"elapsed"`
+	// MBps is the throughput in megabytes per second since the previous
+	// update, and AverageMBps the throughput since the pull started.
+	MBps        float64 `json:"mbps"`
+	AverageMBps float64 `json:"averageMBps"`
+}
+
+// PullSummary summarizes a completed pull.
+type PullSummary struct {
+	Bytes    int64         `json:"bytes"`
+	Duration time.Duration `json:"duration"`
+	MBps     float64       `json:"mbps"`
+}
+
+var (
+	// pullSizePattern matches the line in which Tart announces the size of
+	// the disk it's about to pull, e.g. "pulling disk (20.4 GB compressed)...".
+	pullSizePattern = regexp.MustCompile(`pulling disk \(([\d.]+) ([KMGT]?B) compressed\)`)
+	// pullPercentPattern matches a progress update such as "42%".
+	pullPercentPattern = regexp.MustCompile(`^\s*(\d{1,3}(?:\.\d+)?)%\s*$`)
+)
+
+// byteUnits maps the decimal units Tart prints sizes in to their values.
+var byteUnits = map[string]float64{"B": 1, "KB": 1e3, "MB": 1e6, "GB": 1e9, "TB": 1e12}
+
+// pullProgressWriter parses Tart's pull output into progress updates.
+type pullProgressWriter struct {
+	clock clock
+	fn    func(PullProgress)
+	start time.Time
+
+	buf    []byte
+	total  int64
+	last   PullProgress
+	lastAt time.Time
+}
+
+// Write splits the output into lines, treating carriage returns as line
+// breaks since progress may be redrawn in place. It never fails, so it can't
+// interrupt the command.
+func (w *pullProgressWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexAny(w.buf, "\r\n")
+		if i < 0 {
+			break
+		}
+		w.line(string(w.buf[:i]))
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// line handles a single line of output.
+func (w *pullProgressWriter) line(line string) {
+	if m := pullSizePattern.FindStringSubmatch(line); m != nil {
+		size, _ := strconv.ParseFloat(m[1], 64)
+		w.total = int64(size * byteUnits[m[2]])
+		return
+	}
+	m := pullPercentPattern.FindStringSubmatch(line)
+	if m == nil {
+		return
+	}
+	percent, _ := strconv.ParseFloat(m[1], 64)
+	if percent > 100 {
+		percent = 100
+	}
+	now := w.clock.Now()
+	p := PullProgress{
+		Percent:    percent,
+		Bytes:      int64(percent / 100 * float64(w.total)),
+		TotalBytes: w.total,
+		Elapsed:    now.Sub(w.start),
+	}
+	p.AverageMBps = mbps(p.Bytes, p.Elapsed)
+	p.MBps = p.AverageMBps
+	if !w.lastAt.IsZero() {
+		p.MBps = mbps(p.Bytes-w.last.Bytes, now.Sub(w.lastAt))
+	}
+	w.last, w.lastAt = p, now
+	if w.fn != nil {
+		w.fn(p)
+	}
+}
+
+// mbps returns the throughput in megabytes per second.
+func mbps(bytes int64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(bytes) / 1e6 / d.Seconds()
+}
+
+// PullWithProgress pulls a VM from a registry like PullContext, calling fn
+// with each progress update Tart prints. Updates are delivered from the
+// goroutine reading Tart's output, so fn should return quickly.
+// The summary's byte count covers only what was reported as transferred,
+// so it's zero if the image was already cached.
+// It returns a summary of the pull, and an error if the options are invalid
+// or if the pull process fails.
+func (t *Tart) PullWithProgress(ctx context.Context, name string, options PullOptions, fn func(PullProgress)) (PullSummary, error) {
+	args, err := t.pullArgs(name, options)
+	if err != nil {
+		return PullSummary{}, err
+	}
+	c := t.clock()
+	w := &pullProgressWriter{clock: c, fn: fn, start: c.Now()}
+	ctx, done := t.trackPull(ctx, name)
+	defer done()
+	output, err := t.runStream(ctx, options.Credentials.env(), w, args...)
+	if err != nil {
+		return PullSummary{}, fmt.Errorf("failed to pull VM: %w, output: %s", err, string(output))
+	}
+	summary := PullSummary{Bytes: w.last.Bytes, Duration: c.Now().Sub(w.start)}
+	summary.MBps = mbps(summary.Bytes, summary.Duration)
+	return summary, nil
+}
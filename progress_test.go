@@ -0,0 +1,54 @@
+package tart
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPullProgressWriter(t *testing.T) {
+	clk := newFakeClock()
+	var updates []PullProgress
+	w := &pullProgressWriter{clock: clk, fn: func(p PullProgress) { updates = append(updates, p) }, start: clk.Now()}
+	w.Write([]byte("pulling manifest...\npulling disk (0.1 GB compressed)...\n"))
+	clk.advance(time.Second)
+	w.Write([]byte("10"))
+	w.Write([]byte("%\r"))
+	clk.advance(time.Second)
+	w.Write([]byte("30%\rnot progress\n"))
+	clk.advance(2 * time.Second)
+	w.Write([]byte("120%\n"))
+	want := []PullProgress{
+		{Percent: 10, Bytes: 10e6, TotalBytes: 100e6, Elapsed: time.Second, MBps: 10, AverageMBps: 10},
+		{Percent: 30, Bytes: 30e6, TotalBytes: 100e6, Elapsed: 2 * time.Second, MBps: 20, AverageMBps: 15},
+		{Percent: 100, Bytes: 100e6, TotalBytes: 100e6, Elapsed: 4 * time.Second, MBps: 35, AverageMBps: 25},
+	}
+	if !reflect.DeepEqual(updates, want) {
+		t.Errorf("updates = %+v, want %+v", updates, want)
+	}
+}
+
+func TestPullWithProgress(t *testing.T) {
+	f := newFakeTart(t, `
+if [ "$1" = pull ]; then
+	echo "pulling disk (2 GB compressed)..."
+	printf '50%%\r'
+	printf '100%%\n'
+	exit 0
+fi`)
+	f.clk = newFakeClock()
+	var percents []float64
+	summary, err := f.PullWithProgress(context.Background(), "ghcr.io/org/img", PullOptions{}, func(p PullProgress) {
+		percents = append(percents, p.Percent)
+	})
+	if err != nil {
+		t.Fatalf("PullWithProgress: %v", err)
+	}
+	if !reflect.DeepEqual(percents, []float64{50, 100}) {
+		t.Errorf("progress = %v, want [50 100]", percents)
+	}
+	if summary.Bytes != 2e9 {
+		t.Errorf("summary = %+v, want 2 GB transferred", summary)
+	}
+}
@@ -1,6 +1,9 @@
 package tart
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 // LoginOptions represents options for logging in to a registry.
 type LoginOptions struct {
@@ -47,6 +50,26 @@ func (t *Tart) Logout() error {
 	return nil
 }
 
+// Credentials represents registry credentials scoped to a single command.
+// They're passed to Tart through its TART_REGISTRY_USERNAME and
+// TART_REGISTRY_PASSWORD environment variables, so they're never written to
+// the credential store nor included in arguments or error messages.
+type Credentials struct {
+	Username string `json:"username"`
+	Password string `json:"-"`
+}
+
+// env returns the environment variables that pass the credentials to Tart.
+func (c *Credentials) env() []string {
+	if c == nil {
+		return nil
+	}
+	return []string{
+		"TART_REGISTRY_USERNAME=" + c.Username,
+		"TART_REGISTRY_PASSWORD=" + c.Password,
+	}
+}
+
 // PushOptions represents the options for pushing a VM to a registry.
 type PushOptions struct {
 	RemoteNames   []string `json:"remoteNames"`
@@ -56,23 +79,56 @@ type PushOptions struct {
 	PopulateCache bool     `json:"populateCache"`
 }
 
-// Push pushes a VM to a registry.
-// It returns an error if the push process fails.
-func (t *Tart) Push(name string, options PushOptions) error {
+// transferDefaults fills in zero concurrency and chunk size values with the
+// instance's defaults.
+// It returns an error if the resulting values are out of range.
+func (t *Tart) transferDefaults(concurrency int, chunkSize int) (int, int, error) {
+	if concurrency == 0 {
+		concurrency = t.DefaultConcurrency
+	}
+	if chunkSize == 0 {
+		chunkSize = t.DefaultChunkSize
+	}
+	if concurrency < 0 {
+		return 0, 0, fmt.Errorf("invalid concurrency: %d", concurrency)
+	}
+	if chunkSize < 0 {
+		return 0, 0, fmt.Errorf("invalid chunk size: %d", chunkSize)
+	}
+	return concurrency, chunkSize, nil
+}
+
+// pushArgs builds the arguments for pushing a VM.
+// It returns an error if the options are invalid.
+func (t *Tart) pushArgs(name string, options PushOptions) ([]string, error) {
+	concurrency, chunkSize, err := t.transferDefaults(options.Concurrency, options.ChunkSize)
+	if err != nil {
+		return nil, err
+	}
 	args := []string{"push", name}
 	args = append(args, options.RemoteNames...)
 	if options.Insecure {
 		args = append(args, "--insecure")
 	}
-	if options.Concurrency > 0 {
-		args = append(args, "--concurrency", fmt.Sprintf("%d", options.Concurrency))
+	if concurrency > 0 {
+		args = append(args, "--concurrency", fmt.Sprintf("%d", concurrency))
 	}
-	if options.ChunkSize > 0 {
-		args = append(args, "--chunk-size", fmt.Sprintf("%d", options.ChunkSize))
+	if chunkSize > 0 {
+		args = append(args, "--chunk-size", fmt.Sprintf("%d", chunkSize))
 	}
 	if options.PopulateCache {
 		args = append(args, "--populate-cache")
 	}
+	return args, nil
+}
+
+// Push pushes a VM to a registry.
+// It returns an error if the push process fails.
+func (t *Tart) Push(name string, options PushOptions) error {
+	args, err := t.pushArgs(name, options)
+	if err != nil {
+		return err
+	}
 	output, err := t.run(args...)
 	if err != nil {
 		return fmt.Errorf("failed to push VM: %w, output: %s", err, string(output))
@@ -80,17 +136,122 @@ func (t *Tart) Push(name string, options PushOptions) error {
 	return nil
 }
 
+// PullOptions represents the options for pulling a VM from a registry.
+//
+// Pulled layers always land in the local OCI cache; Tart has no flag to
+// bypass it, so use Prune to keep the cache in check on ephemeral hosts.
+type PullOptions struct {
+	Insecure    bool `json:"insecure"`
+	Concurrency int  `json:"concurrency"`
+	// Deduplicate expands the image using APFS copy-on-write clones of the
+	// layers in the local OCI cache instead of copying them.
+	Deduplicate bool `json:"deduplicate"`
+	// Credentials, if set, authenticate this pull only.
+	Credentials *Credentials `json:"-"`
+}
+
 // Pull pulls a VM from a registry.
 // It returns an error if the pull process fails.
 func (t *Tart) Pull(name string, insecure bool, concurrency int) error {
+	return t.PullWithOptions(name, PullOptions{
+		Insecure:    insecure,
+		Concurrency: concurrency,
+	})
+}
+
+// pullArgs builds the arguments for pulling a VM.
+// It returns an error if the options are invalid.
+func (t *Tart) pullArgs(name string, options PullOptions) ([]string, error) {
+	concurrency, _, err := t.transferDefaults(options.Concurrency, 0)
+	if err != nil {
+		return nil, err
+	}
 	args := []string{"pull", name}
-	if insecure {
+	if options.Insecure {
 		args = append(args, "--insecure")
 	}
 	if concurrency > 0 {
 		args = append(args, "--concurrency", fmt.Sprintf("%d", concurrency))
 	}
-	output, err := t.run(args...)
+	if options.Deduplicate {
+		args = append(args, "--deduplicate")
+	}
+	return args, nil
+}
+
+// PullWithOptions pulls a VM from a registry with the specified options.
+// It returns an error if the options are invalid or if the pull process fails.
+func (t *Tart) PullWithOptions(name string, options PullOptions) error {
+	return t.PullContext(context.Background(), name, options)
+}
+
+// pullOp is an in-flight pull or clone that CancelPull can abort.
+type pullOp struct {
+	cancel context.CancelFunc
+}
+
+// trackPull registers an in-flight transfer of ref, returning a context
+// derived from ctx that CancelPull cancels and a function to call once the
+// transfer is finished.
+func (t *Tart) trackPull(ctx context.Context, ref string) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	op := &pullOp{cancel: cancel}
+	t.mu.Lock()
+	if t.pulls == nil {
+		t.pulls = map[string][]*pullOp{}
+	}
+	t.pulls[ref] = append(t.pulls[ref], op)
+	t.mu.Unlock()
+	return ctx, func() {
+		t.mu.Lock()
+		// Build a new slice rather than editing in place, so a concurrent
+		// CancelPull iterating a copy is unaffected.
+		var ops []*pullOp
+		for _, o := range t.pulls[ref] {
+			if o != op {
+				ops = append(ops, o)
+			}
+		}
+		if len(ops) == 0 {
+			delete(t.pulls, ref)
+		} else {
+			t.pulls[ref] = ops
+		}
+		t.mu.Unlock()
+		cancel()
+	}
+}
+
+// CancelPull aborts the pulls of a reference in progress on this instance,
+// and clones from it, killing their tart processes, for example to stop a
+// stuck transfer from an interactive tool without cancelling everything
+// else. The aborted calls return an error wrapping context.Canceled.
+// It returns an error wrapping ErrNoPullInProgress if there's nothing to
+// cancel.
+func (t *Tart) CancelPull(ref string) error {
+	t.mu.Lock()
+	ops := append([]*pullOp(nil), t.pulls[ref]...)
+	t.mu.Unlock()
+	if len(ops) == 0 {
+		return fmt.Errorf("%w: %s", ErrNoPullInProgress, ref)
+	}
+	for _, op := range ops {
+		op.cancel()
+	}
+	return nil
+}
+
+// PullContext pulls a VM from a registry with the specified options,
+// aborting the pull if the context is done before it completes.
+// It returns an error if the options are invalid or if the pull process fails.
+func (t *Tart) PullContext(ctx context.Context, name string, options PullOptions) error {
+	args, err := t.pullArgs(name, options)
+	if err != nil {
+		return err
+	}
+	ctx, done := t.trackPull(ctx, name)
+	defer done()
+	output, err := t.runEnv(ctx, options.Credentials.env(), args...)
 	if err != nil {
 		return fmt.Errorf("failed to pull VM: %w, output: %s", err, string(output))
 	}
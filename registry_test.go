@@ -0,0 +1,231 @@
+package tart
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowPullScript is a fake tart script whose pull runs for up to 30 seconds.
+const slowPullScript = `
+case "$1" in
+pull)
+	i=0
+	while [ $i -lt 600 ]; do sleep 0.05; i=$((i+1)); done
+	exit 0;;
+esac`
+
+func TestCancelPull(t *testing.T) {
+	f := newFakeTart(t, slowPullScript)
+	const ref = "ghcr.io/org/image:latest"
+	const pulls = 2
+	errs := make(chan error, pulls)
+	var started sync.WaitGroup
+	for i := 0; i < pulls; i++ {
+		started.Add(1)
+		go func() {
+			started.Done()
+			errs <- f.PullContext(context.Background(), ref, PullOptions{})
+		}()
+	}
+	started.Wait()
+	deadline := time.Now().Add(10 * time.Second)
+	for len(f.commandCalls(t, "pull")) < pulls {
+		if time.Now().After(deadline) {
+			t.Fatal("pulls didn't start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := f.CancelPull(ref); err != nil {
+		t.Fatalf("CancelPull: %v", err)
+	}
+	for i := 0; i < pulls; i++ {
+		select {
+		case err := <-errs:
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("PullContext = %v, want context.Canceled", err)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("a pull wasn't cancelled")
+		}
+	}
+	if err := f.CancelPull(ref); !errors.Is(err, ErrNoPullInProgress) {
+		t.Errorf("CancelPull with nothing in progress = %v, want ErrNoPullInProgress", err)
+	}
+}
+
+func TestCancelPullWhilePullsFinish(t *testing.T) {
+	f := newFakeTart(t, "")
+	const ref = "ghcr.io/org/image:latest"
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, done := f.trackPull(context.Background(), ref)
+			done()
+		}()
+		go func() {
+			defer wg.Done()
+			f.CancelPull(ref)
+		}()
+	}
+	wg.Wait()
+	if len(f.pulls) != 0 {
+		t.Errorf("%d references still tracked after every pull finished", len(f.pulls))
+	}
+}
+
+func TestTransferCacheArgs(t *testing.T) {
+	f := newFakeTart(t, "")
+	tests := []struct {
+		name    string
+		plan    func() ([]string, error)
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "pull deduplicated",
+			plan: func() ([]string, error) { return f.PlanPull("ghcr.io/org/image", PullOptions{Deduplicate: true}) },
+			want: []string{"pull", "ghcr.io/org/image", "--deduplicate"},
+		},
+		{
+			name: "clone deduplicated with prune limit",
+			plan: func() ([]string, error) {
+				return f.PlanClone("ghcr.io/org/image", "vm", CloneOptions{Deduplicate: true, PruneLimit: 100})
+			},
+			want: []string{"clone", "ghcr.io/org/image", "vm", "--deduplicate", "--prune-limit", "100"},
+		},
+		{
+			name:    "negative prune limit",
+			plan:    func() ([]string, error) { return f.PlanClone("ghcr.io/org/image", "vm", CloneOptions{PruneLimit: -5}) },
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.plan()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransferDefaults(t *testing.T) {
+	f := newFakeTart(t, "")
+	f.DefaultConcurrency = 8
+	f.DefaultChunkSize = 50
+	tests := []struct {
+		name    string
+		plan    func() ([]string, error)
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "push defaults",
+			plan: func() ([]string, error) {
+				return f.PlanPush("vm", PushOptions{RemoteNames: []string{"ghcr.io/org/image"}})
+			},
+			want: []string{"push", "vm", "ghcr.io/org/image", "--concurrency", "8", "--chunk-size", "50"},
+		},
+		{
+			name: "push overrides",
+			plan: func() ([]string, error) {
+				return f.PlanPush("vm", PushOptions{RemoteNames: []string{"ghcr.io/org/image"}, Concurrency: 2, ChunkSize: 10})
+			},
+			want: []string{"push", "vm", "ghcr.io/org/image", "--concurrency", "2", "--chunk-size", "10"},
+		},
+		{
+			name: "pull default concurrency",
+			plan: func() ([]string, error) { return f.PlanPull("ghcr.io/org/image", PullOptions{}) },
+			want: []string{"pull", "ghcr.io/org/image", "--concurrency", "8"},
+		},
+		{
+			name: "clone default concurrency",
+			plan: func() ([]string, error) { return f.PlanClone("ghcr.io/org/image", "vm", CloneOptions{}) },
+			want: []string{"clone", "ghcr.io/org/image", "vm", "--concurrency", "8"},
+		},
+		{
+			name:    "negative concurrency",
+			plan:    func() ([]string, error) { return f.PlanPull("ghcr.io/org/image", PullOptions{Concurrency: -1}) },
+			wantErr: true,
+		},
+		{
+			name:    "negative chunk size",
+			plan:    func() ([]string, error) { return f.PlanPush("vm", PushOptions{ChunkSize: -1}) },
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.plan()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransferCredentials(t *testing.T) {
+	creds := &Credentials{Username: "bot", Password: "hunter2"}
+	tests := []struct {
+		name     string
+		transfer func(f *fakeTart) error
+	}{
+		{name: "pull", transfer: func(f *fakeTart) error {
+			return f.PullWithOptions("ghcr.io/org/img", PullOptions{Credentials: creds})
+		}},
+		{name: "clone", transfer: func(f *fakeTart) error {
+			return f.Clone("ghcr.io/org/img", "vm", CloneOptions{Credentials: creds})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeTart(t, `
+case "$1" in
+pull|clone)
+	echo "$TART_REGISTRY_USERNAME:$TART_REGISTRY_PASSWORD" >> "$FAKE_TART_DIR/creds"
+	echo "authenticated as $TART_REGISTRY_USERNAME" >&2
+	exit 1;;
+esac`)
+			f.DebugLogPath = filepath.Join(f.dir, "debug.log")
+			err := tt.transfer(f)
+			if err == nil {
+				t.Fatal("transfer succeeded, want the fake's failure")
+			}
+			seen, err2 := os.ReadFile(filepath.Join(f.dir, "creds"))
+			if err2 != nil {
+				t.Fatal(err2)
+			}
+			if string(seen) != "bot:hunter2\n" {
+				t.Errorf("tart saw credentials %q, want bot:hunter2", seen)
+			}
+			log, err2 := os.ReadFile(f.DebugLogPath)
+			if err2 != nil {
+				t.Fatal(err2)
+			}
+			if strings.Contains(string(log), "hunter2") || strings.Contains(err.Error(), "hunter2") {
+				t.Errorf("password leaked into the debug log or error:\n%s\n%v", log, err)
+			}
+			if err := f.PullWithOptions("ghcr.io/org/img", PullOptions{}); err == nil {
+				t.Fatal("second transfer succeeded, want the fake's failure")
+			}
+			if seen, _ := os.ReadFile(filepath.Join(f.dir, "creds")); !strings.HasSuffix(string(seen), "\n:\n") {
+				t.Errorf("credentials persisted to a later pull: %q", seen)
+			}
+		})
+	}
+}
@@ -1,10 +1,13 @@
 package tart
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
 	"io"
-	"os/exec"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -17,7 +20,149 @@ type DirMount struct {
 	Tag      string `json:"tag"`
 }
 
+// arg renders the mount as the value of a --dir flag.
+func (d DirMount) arg() string {
+	arg := d.Path
+	if d.Name != "" {
+		arg = d.Name + ":" + arg
+	}
+	var opts []string
+	if d.ReadOnly {
+		opts = append(opts, "ro")
+	}
+	if d.Tag != "" {
+		opts = append(opts, "tag="+d.Tag)
+	}
+	if d.Sync != "" {
+		opts = append(opts, "sync="+d.Sync)
+	}
+	if len(opts) > 0 {
+		arg += ":" + strings.Join(opts, ",")
+	}
+	return arg
+}
+
+// validateDirs checks a set of mounts against Tart's rules: mounts are
+// grouped into one share per tag (a missing tag means the automount share),
+// a share holding several directories needs each of them named uniquely,
+// and names and tags can't contain the separators of the flag syntax.
+// It returns the problems found.
+func validateDirs(dirs []DirMount) []error {
+	var problems []error
+	var tags []string
+	byTag := map[string][]DirMount{}
+	for _, d := range dirs {
+		if d.Path == "" {
+			problems = append(problems, fmt.Errorf("invalid directory mount %q: path is required", d.Name))
+		}
+		if err := checkArgValue("directory mount path", d.Path, localPathDelimiters(d.Path)); err != nil {
+			problems = append(problems, err)
+		}
+		if err := checkArgValue("directory mount name", d.Name, ":"); err != nil {
+			problems = append(problems, err)
+		}
+		if err := checkArgValue("directory mount tag", d.Tag, ":,"); err != nil {
+			problems = append(problems, err)
+		}
+		if err := checkArgValue("directory mount sync mode", d.Sync, ":,"); err != nil {
+			problems = append(problems, err)
+		}
+		if _, ok := byTag[d.Tag]; !ok {
+			tags = append(tags, d.Tag)
+		}
+		byTag[d.Tag] = append(byTag[d.Tag], d)
+	}
+	for _, tag := range tags {
+		group := byTag[tag]
+		if len(group) < 2 {
+			continue
+		}
+		share := "the automount share"
+		if tag != "" {
+			share = fmt.Sprintf("tag %q", tag)
+		}
+		names := map[string]bool{}
+		for _, d := range group {
+			if d.Name == "" {
+				problems = append(problems, fmt.Errorf("invalid directory mount %s: every mount sharing %s needs a name", d.Path, share))
+				continue
+			}
+			if names[d.Name] {
+				problems = append(problems, fmt.Errorf("invalid directory mount %s: name %q is used twice in %s", d.Path, d.Name, share))
+			}
+			names[d.Name] = true
+		}
+	}
+	return problems
+}
+
+// validateDisks returns the problems with --disk values that Tart would
+// misparse. Options follow the path after its last colon, so a local path
+// can't contain one itself.
+func validateDisks(disks []string) []error {
+	var problems []error
+	for _, disk := range disks {
+		path := diskPath(disk)
+		if path == "" {
+			problems = append(problems, fmt.Errorf("invalid disk %q: path is required", disk))
+			continue
+		}
+		if err := checkArgValue("disk path", path, localPathDelimiters(path)); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	return problems
+}
+
+// localPathDelimiters returns the characters a path can't contain in an
+// option value where ':' introduces the options: none for a URL such as a
+// remote archive or an NBD disk, which Tart recognises by its scheme, and ':'
+// for a local path.
+func localPathDelimiters(path string) string {
+	if strings.Contains(path, "://") {
+		return ""
+	}
+	return ":"
+}
+
+// checkArgValue returns an error if an option value contains any of the
+// delimiters Tart splits it on, or a NUL byte, which can't be passed as a
+// process argument at all. Tart has no escaping syntax for these values, so
+// they can't be represented. Spaces and other shell metacharacters are fine,
+// since arguments are passed to tart directly rather than through a shell.
+func checkArgValue(kind string, value string, delimiters string) error {
+	if i := strings.IndexAny(value, delimiters+"\x00"); i >= 0 {
+		return fmt.Errorf("invalid %s %q: Tart can't represent %q in it", kind, value, value[i])
+	}
+	return nil
+}
+
+// Constants representing the pointing devices a VM can be given.
+const (
+	// PointerDefault leaves the choice to Tart: a trackpad for macOS guests
+	// and a mouse otherwise.
+	PointerDefault = ""
+	// PointerMouse gives macOS guests a mouse instead of a trackpad.
+	PointerMouse = "mouse"
+	// PointerNone gives the VM no pointing device at all.
+	PointerNone = "none"
+)
+
+// Constants representing whether a device shared with the host is enabled.
+const (
+	// DeviceDefault leaves the device in Tart's default state, enabled.
+	DeviceDefault = ""
+	// DeviceEnabled explicitly asks for the device.
+	DeviceEnabled = "enabled"
+	// DeviceDisabled turns the device off.
+	DeviceDisabled = "disabled"
+)
+
 // RunOptions represents the options for running a VM.
+// Tart has no option controlling the guest clock: it starts from the host's
+// real-time clock and the guest keeps it with NTP. Nor can it choose the boot
+// disk; a VM always boots from its root disk, see RootDisk, with any Disk
+// entries attached as additional disks.
 type RunOptions struct {
 	NoGraphics        bool       `json:"noGraphics"`
 	Serial            bool       `json:"serial"`
@@ -37,20 +182,173 @@ type RunOptions struct {
 	RootDiskOpts      string     `json:"rootDiskOpts"`
 	Suspendable       bool       `json:"suspendable"`
 	CaptureSystemKeys bool       `json:"captureSystemKeys"`
+
+	// NetSoftnetAllowCIDRs lists the networks a softnet VM may reach. Each
+	// entry is validated as a CIDR and the list is joined with
+	// NetSoftnetAllow, which is passed through unvalidated.
+	NetSoftnetAllowCIDRs []string `json:"netSoftnetAllowCIDRs"`
+	// CPUCount and MemorySize (in megabytes) override the VM's configuration
+	// for this run only. Tart has no run-time flags for them, so the
+	// configuration is changed with SetConfig before the VM starts and
+	// restored once it's up (or has failed to start).
+	CPUCount   int    `json:"cpuCount"`
+	MemorySize uint64 `json:"memorySize"`
+	// SerialOutput, if set, receives the VM's serial output, both before and
+	// after it's up. Each line is passed to a single Write call.
+	SerialOutput io.Writer `json:"-"`
+	// Nested enables nested virtualization in the guest. It requires macOS 15
+	// and an M3 or newer chip on the host.
+	Nested bool `json:"nested"`
+	// Pointer selects the pointing device, one of the Pointer constants.
+	// Input devices are chosen per run; Tart doesn't store them in the VM's
+	// configuration.
+	Pointer string `json:"pointer"`
+	// NoKeyboard gives the VM no keyboard.
+	NoKeyboard bool `json:"noKeyboard"`
+	// AudioMode and ClipboardMode control audio and clipboard sharing, each
+	// one of the Device constants; DeviceDisabled is equivalent to NoAudio
+	// and NoClipboard respectively. Audio works with or without graphics.
+	// Clipboard sharing goes through the built-in UI, so DeviceEnabled is
+	// rejected with NoGraphics; disabling it headless is harmless.
+	AudioMode     string `json:"audioMode"`
+	ClipboardMode string `json:"clipboardMode"`
+	// MACSeed, if set, gives the VM the MAC address DeterministicMAC derives
+	// from it before the VM starts. Unlike CPUCount and MemorySize the
+	// address is kept after the run, giving recreations of the same logical
+	// VM a stable network identity. It's written to the VM's bundle, so it
+	// isn't supported with a remote Transport.
+	MACSeed string `json:"macSeed"`
+	// SerialBufferSize sets the size in bytes of the buffer the VM's output
+	// is read through. Larger buffers mean fewer reads for chatty guests.
+	// Lines longer than the buffer are still delivered whole. Zero uses the
+	// bufio default of 4096 bytes.
+	SerialBufferSize int `json:"serialBufferSize"`
+	// Softnet, if set, runs the VM with softnet networking configured as
+	// described, implying NetSoftnet. Its allow-list is combined with
+	// NetSoftnetAllow and NetSoftnetAllowCIDRs.
+	Softnet *SoftnetConfig `json:"softnet,omitempty"`
 }
 
-// Run runs a VM with the specified options.
-// It returns an error if the VM is already running, doesn't exist, or if the run process fails.
-func (t *Tart) Run(name string, options RunOptions) error {
-	s, err := t.State(name)
-	if err != nil {
-		return fmt.Errorf("failed to get VM state: %w", err)
+// diskPath returns the path or URL of a --disk value, without any trailing
+// options such as ":ro" or ":sync=none".
+func diskPath(disk string) string {
+	i := strings.LastIndex(disk, ":")
+	if i < 0 {
+		return disk
 	}
-	if s.State == "running" {
-		return fmt.Errorf("VM is already running")
+	for _, opt := range strings.Split(disk[i+1:], ",") {
+		if opt != "ro" && !strings.HasPrefix(opt, "sync=") && !strings.HasPrefix(opt, "caching=") {
+			return disk
+		}
 	}
-	if s.Name != name {
-		return fmt.Errorf("VM with name %s does not exist", name)
+	return disk[:i]
+}
+
+// RootDisk returns the path of the disk image a VM boots from, which for
+// Tart is always the root disk in its bundle.
+// It returns an error wrapping ErrVMNotFound if the VM doesn't exist.
+func (t *Tart) RootDisk(name string) (string, error) {
+	if err := t.requireLocal("RootDisk"); err != nil {
+		return "", err
+	}
+	path := filepath.Join(t.vmDir(name), "disk.img")
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("%w: %s", ErrVMNotFound, name)
+		}
+		return "", fmt.Errorf("failed to inspect VM disk: %w", err)
+	}
+	return path, nil
+}
+
+// Validate checks the options for problems that Tart would reject or
+// silently misinterpret.
+// It returns a *ValidationError listing every problem found, or nil.
+func (o RunOptions) Validate() error {
+	var problems []error
+	if o.NoGraphics {
+		if gui := o.guiOnly(); len(gui) > 0 {
+			problems = append(problems, fmt.Errorf("GUI-only options can't be combined with NoGraphics: %s", strings.Join(gui, ", ")))
+		}
+	}
+	for _, mode := range [][2]string{{"AudioMode", o.AudioMode}, {"ClipboardMode", o.ClipboardMode}} {
+		switch mode[1] {
+		case DeviceDefault, DeviceEnabled, DeviceDisabled:
+		default:
+			problems = append(problems, fmt.Errorf("invalid %s: %s", mode[0], mode[1]))
+		}
+	}
+	if o.NoAudio && o.AudioMode == DeviceEnabled {
+		problems = append(problems, errors.New("NoAudio conflicts with AudioMode enabled"))
+	}
+	if o.NoClipboard && o.ClipboardMode == DeviceEnabled {
+		problems = append(problems, errors.New("NoClipboard conflicts with ClipboardMode enabled"))
+	}
+	switch o.Pointer {
+	case PointerDefault, PointerMouse, PointerNone:
+	default:
+		problems = append(problems, fmt.Errorf("invalid pointer: %s", o.Pointer))
+	}
+	var networks []string
+	if o.NetBridged != "" {
+		networks = append(networks, "NetBridged")
+	}
+	if o.NetSoftnet || o.Softnet != nil {
+		networks = append(networks, "NetSoftnet")
+	}
+	if o.NetHost {
+		networks = append(networks, "NetHost")
+	}
+	if len(networks) > 1 {
+		problems = append(problems, fmt.Errorf("network options are mutually exclusive: %s", strings.Join(networks, ", ")))
+	}
+	if o.SerialBufferSize < 0 {
+		problems = append(problems, fmt.Errorf("invalid serial buffer size: %d", o.SerialBufferSize))
+	}
+	if o.Softnet != nil {
+		problems = append(problems, o.Softnet.validate()...)
+	}
+	problems = append(problems, validateDirs(o.Dir)...)
+	problems = append(problems, validateDisks(o.Disk)...)
+	for _, cidr := range o.NetSoftnetAllowCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			problems = append(problems, fmt.Errorf("invalid softnet allow CIDR %q: %w", cidr, err))
+		}
+	}
+	return validationError(problems)
+}
+
+// guiOnly returns the names of the options that are set and only take effect
+// in the built-in graphical UI.
+func (o RunOptions) guiOnly() []string {
+	var gui []string
+	if o.CaptureSystemKeys {
+		gui = append(gui, "CaptureSystemKeys")
+	}
+	if o.ClipboardMode == DeviceEnabled {
+		gui = append(gui, "ClipboardMode")
+	}
+	return gui
+}
+
+// softnetAllow returns the combined softnet allow-list.
+func (o RunOptions) softnetAllow() string {
+	var allow []string
+	if o.NetSoftnetAllow != "" {
+		allow = append(allow, o.NetSoftnetAllow)
+	}
+	allow = append(allow, o.NetSoftnetAllowCIDRs...)
+	if o.Softnet != nil {
+		allow = append(allow, o.Softnet.allow()...)
+	}
+	return strings.Join(allow, ",")
+}
+
+// runArgs builds the arguments for running a VM with the specified options.
+// It returns an error if the options are invalid.
+func runArgs(name string, options RunOptions) ([]string, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
 	}
 	args := []string{"run"}
 	if options.NoGraphics {
@@ -62,10 +360,10 @@ func (t *Tart) Run(name string, options RunOptions) error {
 	if options.SerialPath != "" {
 		args = append(args, "--serial-path", options.SerialPath)
 	}
-	if options.NoAudio {
+	if options.NoAudio || options.AudioMode == DeviceDisabled {
 		args = append(args, "--no-audio")
 	}
-	if options.NoClipboard {
+	if options.NoClipboard || options.ClipboardMode == DeviceDisabled {
 		args = append(args, "--no-clipboard")
 	}
 	if options.Recovery {
@@ -84,36 +382,24 @@ func (t *Tart) Run(name string, options RunOptions) error {
 		args = append(args, "--rosetta", options.Rosetta)
 	}
 	for _, dir := range options.Dir {
-		dirArg := ""
-		if dir.Name != "" {
-			dirArg += dir.Name + ":"
-		}
-		dirArg += dir.Path
-		if dir.ReadOnly || dir.Tag != "" {
-			dirArg += ":"
-			if dir.ReadOnly {
-				dirArg += "ro"
-				if dir.Tag != "" {
-					dirArg += ","
-				}
-			}
-			if dir.Tag != "" {
-				dirArg += "tag=" + dir.Tag
-			}
-			if dir.Sync != "" {
-				dirArg += "sync=" + dir.Sync
-			}
-		}
-		args = append(args, "--dir", dirArg)
+		args = append(args, "--dir", dir.arg())
 	}
 	if options.NetBridged != "" {
 		args = append(args, "--net-bridged", options.NetBridged)
 	}
-	if options.NetSoftnet {
+	if options.NetSoftnet || options.Softnet != nil {
 		args = append(args, "--net-softnet")
 	}
-	if options.NetSoftnetAllow != "" {
-		args = append(args, "--net-softnet-allow", options.NetSoftnetAllow)
+	if allow := options.softnetAllow(); allow != "" {
+		args = append(args, "--net-softnet-allow", allow)
+	}
+	if options.Softnet != nil {
+		if len(options.Softnet.Block) > 0 {
+			args = append(args, "--net-softnet-block", strings.Join(options.Softnet.Block, ","))
+		}
+		if len(options.Softnet.Expose) > 0 {
+			args = append(args, "--net-softnet-expose", options.Softnet.expose())
+		}
 	}
 	if options.NetHost {
 		args = append(args, "--net-host")
@@ -127,38 +413,70 @@ func (t *Tart) Run(name string, options RunOptions) error {
 	if options.CaptureSystemKeys {
 		args = append(args, "--capture-system-keys")
 	}
+	if options.Nested {
+		args = append(args, "--nested")
+	}
+	switch options.Pointer {
+	case PointerMouse:
+		args = append(args, "--no-trackpad")
+	case PointerNone:
+		args = append(args, "--no-pointer")
+	}
+	if options.NoKeyboard {
+		args = append(args, "--no-keyboard")
+	}
 	args = append(args, name)
+	return args, nil
+}
 
-	cmd := exec.Command("tart", args...)
-	t.setTartHome(cmd)
-
-	serialOut, err := cmd.StdoutPipe()
+// checkRunnable returns an error if a VM is already running or doesn't exist.
+func (t *Tart) checkRunnable(name string) error {
+	s, err := t.State(name)
 	if err != nil {
-		return fmt.Errorf("failed to create stdout pipe: %w", err)
+		return fmt.Errorf("failed to get VM state: %w", err)
 	}
-
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start VM: %w", err)
+	if s.State == "running" {
+		return fmt.Errorf("%w: %s", ErrVMAlreadyRunning, name)
 	}
-
-	reader := bufio.NewReader(serialOut)
-	for {
-		line, err := reader.ReadString('\n')
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return fmt.Errorf("failed to read serial output: %w", err)
-		}
-		if strings.Contains(line, "VM is up") {
-			fmt.Println("VM is up and running")
-			return nil
-		}
+	if s.Name != name {
+		return fmt.Errorf("%w: %s", ErrVMNotFound, name)
 	}
+	return nil
+}
 
-	if err := cmd.Wait(); err != nil {
-		return fmt.Errorf("VM process exited with error: %w", err)
+// overrideResources applies the run's CPU and memory overrides to the VM's
+// configuration, returning a function that restores the previous values.
+func (t *Tart) overrideResources(name string, options RunOptions) (func() error, error) {
+	if options.CPUCount <= 0 && options.MemorySize == 0 {
+		return func() error { return nil }, nil
+	}
+	current, err := t.Config(name)
+	if err != nil {
+		return nil, err
 	}
+	override := VMConfig{CPUCount: options.CPUCount, MemorySize: options.MemorySize}
+	if err := t.SetConfig(name, override); err != nil {
+		return nil, err
+	}
+	previous := VMConfig{CPUCount: current.CPUCount, MemorySize: current.MemorySize}
+	return func() error {
+		if err := t.SetConfig(name, previous); err != nil {
+			return fmt.Errorf("failed to restore VM resources: %w", err)
+		}
+		return nil
+	}, nil
+}
 
-	return nil
+// Run runs a VM with the specified options.
+// It returns once the VM is up, leaving it running in the background. The VM's
+// output keeps being drained after that, so a guest writing heavily to its
+// console can't stall on a full pipe; set SerialOutput to capture it, or use
+// RunDetached to get a handle for managing the VM.
+// Like RunUntilLog, concurrent calls for the same VM are serialized.
+// It returns an error wrapping ErrVMAlreadyRunning if the VM is already
+// running, one wrapping ErrVMNotFound if it doesn't exist, or an error if
+// the run process fails.
+func (t *Tart) Run(name string, options RunOptions) error {
+	_, err := t.RunDetached(name, options)
+	return err
 }
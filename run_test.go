@@ -0,0 +1,329 @@
+package tart
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// runArgsTest is a table entry for runArgs.
+type runArgsTest struct {
+	name    string
+	options RunOptions
+	want    []string
+	wantErr bool
+}
+
+// testRunArgs checks runArgs against each entry.
+func testRunArgs(t *testing.T, tests []runArgsTest) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := runArgs("vm", tt.options)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runArgs error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("runArgs = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunFlagNeedsSupport(t *testing.T) {
+	f := newFakeTart(t, `
+case "$2" in
+--help) echo "--no-graphics   Run without graphics"; exit 0;;
+esac`)
+	err := f.Run("vm", RunOptions{Suspendable: true})
+	if !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Run = %v, want ErrUnsupported", err)
+	}
+	if runs := f.commandCalls(t, "run"); len(runs) != 1 || runs[0][1] != "--help" {
+		t.Errorf("run calls = %q, want only the help probe", runs)
+	}
+}
+
+func TestRootDisk(t *testing.T) {
+	f := newFakeTart(t, "")
+	f.addVM(t, "vm", `{}`)
+	disk := filepath.Join(f.vmDir("vm"), "disk.img")
+	if err := os.WriteFile(disk, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		want    string
+		wantErr error
+	}{
+		{name: "vm", want: disk},
+		{name: "missing", wantErr: ErrVMNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := f.RootDisk(tt.name)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RootDisk error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("RootDisk = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunArgsNested(t *testing.T) {
+	testRunArgs(t, []runArgsTest{
+		{name: "off", options: RunOptions{}, want: []string{"run", "vm"}},
+		{name: "on", options: RunOptions{Nested: true}, want: []string{"run", "--nested", "vm"}},
+	})
+}
+
+func TestRunNestedNeedsHostSupport(t *testing.T) {
+	if runtime.GOOS == "darwin" {
+		t.Skip("the host may support nested virtualization")
+	}
+	f := newFakeTart(t, `
+case "$2" in
+--help) echo "--nested   Enable nested virtualization"; exit 0;;
+esac`)
+	err := f.Run("vm", RunOptions{Nested: true})
+	if !errors.Is(err, ErrNestedUnsupported) {
+		t.Errorf("Run = %v, want ErrNestedUnsupported", err)
+	}
+	if runs := f.commandCalls(t, "run"); len(runs) != 1 || runs[0][1] != "--help" {
+		t.Errorf("run calls = %q, want only the help probe", runs)
+	}
+}
+
+func TestRunRosettaNeedsHostSupport(t *testing.T) {
+	available, err := RosettaAvailable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if available {
+		t.Skip("the host has Rosetta installed")
+	}
+	f := newFakeTart(t, `
+case "$2" in
+--help) echo "--rosetta   Attaches a Rosetta share"; exit 0;;
+esac`)
+	err = f.Run("vm", RunOptions{Rosetta: "rosetta"})
+	if !errors.Is(err, ErrRosettaUnavailable) {
+		t.Errorf("Run = %v, want ErrRosettaUnavailable", err)
+	}
+	if runs := f.commandCalls(t, "run"); len(runs) != 1 || runs[0][1] != "--help" {
+		t.Errorf("run calls = %q, want only the help probe", runs)
+	}
+}
+
+func TestRunArgsInputDevices(t *testing.T) {
+	testRunArgs(t, []runArgsTest{
+		{name: "default pointer", options: RunOptions{Pointer: PointerDefault}, want: []string{"run", "vm"}},
+		{name: "mouse", options: RunOptions{Pointer: PointerMouse}, want: []string{"run", "--no-trackpad", "vm"}},
+		{name: "no pointer", options: RunOptions{Pointer: PointerNone}, want: []string{"run", "--no-pointer", "vm"}},
+		{name: "no keyboard", options: RunOptions{NoKeyboard: true}, want: []string{"run", "--no-keyboard", "vm"}},
+		{
+			name:    "no input devices",
+			options: RunOptions{Pointer: PointerNone, NoKeyboard: true},
+			want:    []string{"run", "--no-pointer", "--no-keyboard", "vm"},
+		},
+		{name: "invalid pointer", options: RunOptions{Pointer: "stylus"}, wantErr: true},
+	})
+}
+
+func TestDirMountArg(t *testing.T) {
+	tests := []struct {
+		name  string
+		mount DirMount
+		want  string
+	}{
+		{name: "anonymous", mount: DirMount{Path: "/src"}, want: "/src"},
+		{name: "named", mount: DirMount{Name: "src", Path: "/src"}, want: "src:/src"},
+		{name: "anonymous read-only", mount: DirMount{Path: "/src", ReadOnly: true}, want: "/src:ro"},
+		{
+			name:  "named with options",
+			mount: DirMount{Name: "src", Path: "/src", ReadOnly: true, Tag: "build", Sync: "none"},
+			want:  "src:/src:ro,tag=build,sync=none",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.mount.arg(); got != tt.want {
+				t.Errorf("arg = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateDirs(t *testing.T) {
+	tests := []struct {
+		name     string
+		dirs     []DirMount
+		problems int
+	}{
+		{name: "one anonymous", dirs: []DirMount{{Path: "/a"}}},
+		{name: "named in one share", dirs: []DirMount{{Name: "a", Path: "/a"}, {Name: "b", Path: "/b"}}},
+		{name: "anonymous in separate shares", dirs: []DirMount{{Path: "/a"}, {Path: "/b", Tag: "other"}}},
+		{name: "anonymous in one share", dirs: []DirMount{{Path: "/a"}, {Name: "b", Path: "/b"}}, problems: 1},
+		{name: "duplicate name", dirs: []DirMount{{Name: "a", Path: "/a"}, {Name: "a", Path: "/b"}}, problems: 1},
+		{name: "missing path", dirs: []DirMount{{Name: "a"}}, problems: 1},
+		{name: "colon in name", dirs: []DirMount{{Name: "a:b", Path: "/a"}}, problems: 1},
+		{name: "comma in tag", dirs: []DirMount{{Path: "/a", Tag: "a,b"}}, problems: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if problems := validateDirs(tt.dirs); len(problems) != tt.problems {
+				t.Errorf("validateDirs = %v, want %d problems", problems, tt.problems)
+			}
+		})
+	}
+}
+
+func TestSpecialCharacterPaths(t *testing.T) {
+	testRunArgs(t, []runArgsTest{
+		{
+			name:    "disk with spaces",
+			options: RunOptions{Disk: []string{"/Volumes/My Disk/data (1).img:ro"}},
+			want:    []string{"run", "--disk", "/Volumes/My Disk/data (1).img:ro", "vm"},
+		},
+		{
+			name:    "disk with shell metacharacters",
+			options: RunOptions{Disk: []string{"/tmp/$HOME's & \"disk\";.img"}},
+			want:    []string{"run", "--disk", "/tmp/$HOME's & \"disk\";.img", "vm"},
+		},
+		{
+			name:    "nbd disk",
+			options: RunOptions{Disk: []string{"nbd://localhost:10809/export"}},
+			want:    []string{"run", "--disk", "nbd://localhost:10809/export", "vm"},
+		},
+		{
+			name:    "dir with spaces",
+			options: RunOptions{Dir: []DirMount{{Name: "my src", Path: "/Users/me/My Projects", ReadOnly: true}}},
+			want:    []string{"run", "--dir", "my src:/Users/me/My Projects:ro", "vm"},
+		},
+		{
+			name:    "dir with unicode and commas",
+			options: RunOptions{Dir: []DirMount{{Path: "/Users/me/Données, 2026"}}},
+			want:    []string{"run", "--dir", "/Users/me/Données, 2026", "vm"},
+		},
+		{name: "disk with colon", options: RunOptions{Disk: []string{"/tmp/a:b.img"}}, wantErr: true},
+		{name: "disk with NUL", options: RunOptions{Disk: []string{"/tmp/a\x00b.img"}}, wantErr: true},
+		{name: "dir with colon", options: RunOptions{Dir: []DirMount{{Path: "/tmp/a:b"}}}, wantErr: true},
+	})
+}
+
+func TestValidateGUIOnly(t *testing.T) {
+	tests := []struct {
+		name    string
+		options RunOptions
+		want    string
+	}{
+		{name: "graphics", options: RunOptions{CaptureSystemKeys: true, ClipboardMode: DeviceEnabled}},
+		{name: "no graphics", options: RunOptions{NoGraphics: true, ClipboardMode: DeviceDisabled}},
+		{
+			name:    "capture system keys",
+			options: RunOptions{NoGraphics: true, CaptureSystemKeys: true},
+			want:    "CaptureSystemKeys",
+		},
+		{
+			name:    "every gui-only option",
+			options: RunOptions{NoGraphics: true, CaptureSystemKeys: true, ClipboardMode: DeviceEnabled},
+			want:    "CaptureSystemKeys, ClipboardMode",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.options.Validate()
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("Validate = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), "NoGraphics: "+tt.want) {
+				t.Errorf("Validate = %v, want GUI-only options %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunResourceOverrides(t *testing.T) {
+	tests := []struct {
+		name    string
+		options RunOptions
+		want    [][]string
+	}{
+		{name: "none", options: RunOptions{}, want: [][]string{{"run", "vm"}}},
+		{
+			name:    "cpu and memory",
+			options: RunOptions{CPUCount: 8, MemorySize: 16384},
+			want: [][]string{
+				{"set", "vm", "--cpu", "8", "--memory", "16384"},
+				{"run", "vm"},
+				{"set", "vm", "--cpu", "2", "--memory", "4096"},
+			},
+		},
+		{
+			name:    "cpu only",
+			options: RunOptions{CPUCount: 8},
+			want: [][]string{
+				{"set", "vm", "--cpu", "8"},
+				{"run", "vm"},
+				{"set", "vm", "--cpu", "2", "--memory", "4096"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeTart(t, runningScript)
+			f.setList(t, `[{"name":"vm","state":"stopped"}]`)
+			f.addVM(t, "vm", `{"cpuCount":2,"memorySize":4294967296}`)
+			h, err := f.RunDetached("vm", tt.options)
+			if err != nil {
+				t.Fatalf("RunDetached: %v", err)
+			}
+			defer h.Stop()
+			var got [][]string
+			for _, call := range f.calls(t) {
+				if call[0] == "set" || call[0] == "run" && call[1] != "--help" {
+					got = append(got, call)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("calls = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunArgsDeviceModes(t *testing.T) {
+	testRunArgs(t, []runArgsTest{
+		{name: "defaults", options: RunOptions{}, want: []string{"run", "vm"}},
+		{name: "enabled", options: RunOptions{AudioMode: DeviceEnabled, ClipboardMode: DeviceEnabled}, want: []string{"run", "vm"}},
+		{
+			name:    "disabled",
+			options: RunOptions{AudioMode: DeviceDisabled, ClipboardMode: DeviceDisabled},
+			want:    []string{"run", "--no-audio", "--no-clipboard", "vm"},
+		},
+		{
+			name:    "booleans",
+			options: RunOptions{NoAudio: true, NoClipboard: true},
+			want:    []string{"run", "--no-audio", "--no-clipboard", "vm"},
+		},
+		{
+			name:    "headless audio",
+			options: RunOptions{NoGraphics: true, AudioMode: DeviceEnabled},
+			want:    []string{"run", "--no-graphics", "vm"},
+		},
+		{name: "invalid audio mode", options: RunOptions{AudioMode: "muted"}, wantErr: true},
+		{name: "invalid clipboard mode", options: RunOptions{ClipboardMode: "on"}, wantErr: true},
+		{name: "NoAudio and enabled", options: RunOptions{NoAudio: true, AudioMode: DeviceEnabled}, wantErr: true},
+		{name: "NoClipboard and enabled", options: RunOptions{NoClipboard: true, ClipboardMode: DeviceEnabled}, wantErr: true},
+		{name: "headless clipboard", options: RunOptions{NoGraphics: true, ClipboardMode: DeviceEnabled}, wantErr: true},
+	})
+}
@@ -0,0 +1,120 @@
+package tart
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// serialPollInterval is how often TailSerialLog checks for new output.
+const serialPollInterval = 250 * time.Millisecond
+
+// recordSerialPath remembers the serial path a VM was last run with. It's
+// kept after the VM exits so its log can still be read.
+func (t *Tart) recordSerialPath(name string, path string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.serial == nil {
+		t.serial = map[string]string{}
+	}
+	t.serial[name] = path
+}
+
+// SerialPath returns the SerialPath a VM was last run with by this instance.
+// It returns an error if this instance hasn't run the VM with a serial path.
+func (t *Tart) SerialPath(name string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	path, ok := t.serial[name]
+	if !ok {
+		return "", fmt.Errorf("no serial path recorded for VM %s", name)
+	}
+	return path, nil
+}
+
+// SerialLog reads back the serial output captured for a VM last run by this
+// instance with a SerialPath pointing to a regular file.
+// It returns an error if no serial path was recorded or it can't be read.
+func (t *Tart) SerialLog(name string) ([]byte, error) {
+	path, err := t.SerialPath(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read serial log: %w", err)
+	}
+	return data, nil
+}
+
+// TailSerialLog streams serial output appended to a VM's log, as recorded by
+// SerialLog, to w until the context is done. Only output written after the
+// call is streamed, a line at a time. If the file is truncated the tail
+// continues from its start, and if it's replaced, for example by log
+// rotation, the new file is followed from its start.
+// It returns the context's error once it's done, or an error if the log
+// can't be read or written to w.
+func (t *Tart) TailSerialLog(ctx context.Context, name string, w io.Writer) error {
+	path, err := t.SerialPath(name)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open serial log: %w", err)
+	}
+	defer func() { f.Close() }()
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return fmt.Errorf("failed to read serial log: %w", err)
+	}
+	reader := bufio.NewReader(f)
+	var partial string
+	for {
+		for {
+			line, err := reader.ReadString('\n')
+			offset += int64(len(line))
+			if err != nil {
+				partial += line
+				break
+			}
+			if _, err := io.WriteString(w, partial+line); err != nil {
+				return fmt.Errorf("failed to write serial log: %w", err)
+			}
+			partial = ""
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.clock().After(serialPollInterval):
+		}
+
+		current, err := f.Stat()
+		if err != nil {
+			return fmt.Errorf("failed to read serial log: %w", err)
+		}
+		if latest, err := os.Stat(path); err == nil && !os.SameFile(current, latest) {
+			// Rotated: follow the new file from its start.
+			next, err := os.Open(path)
+			if err != nil {
+				return fmt.Errorf("failed to open serial log: %w", err)
+			}
+			f.Close()
+			f, offset, partial = next, 0, ""
+			reader.Reset(f)
+			continue
+		}
+		if current.Size() < offset {
+			// Truncated: start over from the beginning.
+			if offset, err = f.Seek(0, io.SeekStart); err != nil {
+				return fmt.Errorf("failed to read serial log: %w", err)
+			}
+			partial = ""
+			reader.Reset(f)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package tart
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSerialLog(t *testing.T) {
+	f := newFakeTart(t, `
+case "$2" in
+--help) echo "--serial-path"; exit 0;;
+esac
+if [ "$1" = run ]; then
+	echo "boot log" > "$3"
+fi`+runningScript)
+	f.setList(t, `[{"name":"vm","state":"stopped"}]`)
+	f.WorkDir = t.TempDir()
+	if _, err := f.SerialLog("vm"); err == nil {
+		t.Error("SerialLog before any run succeeded")
+	}
+	h, err := f.RunDetached("vm", RunOptions{SerialPath: "serial.log"})
+	if err != nil {
+		t.Fatalf("RunDetached: %v", err)
+	}
+	h.Stop()
+	<-h.Done()
+	if path, err := f.SerialPath("vm"); err != nil || path != filepath.Join(f.WorkDir, "serial.log") {
+		t.Errorf("SerialPath = %q, %v; want it resolved against WorkDir", path, err)
+	}
+	got, err := f.SerialLog("vm")
+	if err != nil {
+		t.Fatalf("SerialLog after the VM exited: %v", err)
+	}
+	if string(got) != "boot log\n" {
+		t.Errorf("SerialLog = %q, want the boot log", got)
+	}
+}
+
+func TestTailSerialLog(t *testing.T) {
+	f := newFakeTart(t, "")
+	path := filepath.Join(t.TempDir(), "serial.log")
+	if err := os.WriteFile(path, []byte("before the tail\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f.recordSerialPath("vm", path)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var out syncBuffer
+	done := make(chan error, 1)
+	go func() { done <- f.TailSerialLog(ctx, "vm", &out) }()
+
+	appendLog := func(s string) {
+		t.Helper()
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file.WriteString(s)
+		file.Close()
+	}
+	waitFor := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(10 * time.Second)
+		for out.String() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("tailed %q, want %q", out.String(), want)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	// Give the tail time to seek to the end before appending
+	time.Sleep(100 * time.Millisecond)
+	appendLog("login: ")
+	appendLog("ready\n")
+	waitFor("login: ready\n")
+
+	// A rotated log is followed from its start
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("rotated\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("login: ready\nrotated\n")
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("TailSerialLog = %v, want context.Canceled", err)
+	}
+	if strings.Contains(out.String(), "before the tail") {
+		t.Error("TailSerialLog streamed output written before the call")
+	}
+}
@@ -0,0 +1,45 @@
+package tart
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// ServicesStatus reports the availability of the helper programs Tart
+// relies on.
+type ServicesStatus struct {
+	// TartPath is the location of the tart binary, or empty if not found.
+	TartPath string `json:"tartPath"`
+	// SoftnetPath is the location of the softnet binary used for
+	// NetSoftnet, or empty if not found.
+	SoftnetPath string `json:"softnetPath"`
+	// SoftnetSetuid reports whether softnet is setuid root. Otherwise Tart
+	// has to invoke it through sudo, which needs a passwordless sudoers rule.
+	SoftnetSetuid bool `json:"softnetSetuid"`
+}
+
+// Ready reports whether everything needed for softnet networking is in place.
+func (s ServicesStatus) Ready() bool {
+	return s.TartPath != "" && s.SoftnetPath != "" && s.SoftnetSetuid
+}
+
+// ServicesStatus reports whether the helper programs Tart relies on are
+// installed. Tart has no background daemon to query, so this probes for the
+// binaries on the PATH instead.
+// It returns an error if a binary was found but couldn't be inspected.
+func (t *Tart) ServicesStatus() (ServicesStatus, error) {
+	var status ServicesStatus
+	if path, err := exec.LookPath("tart"); err == nil {
+		status.TartPath = path
+	}
+	if path, err := exec.LookPath("softnet"); err == nil {
+		status.SoftnetPath = path
+		info, err := os.Stat(path)
+		if err != nil {
+			return status, fmt.Errorf("failed to inspect softnet: %w", err)
+		}
+		status.SoftnetSetuid = info.Mode()&os.ModeSetuid != 0
+	}
+	return status, nil
+}
@@ -0,0 +1,49 @@
+package tart
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestServicesStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		softnet    os.FileMode
+		wantSetuid bool
+		wantReady  bool
+	}{
+		{name: "no softnet"},
+		{name: "softnet without setuid", softnet: 0755},
+		{name: "setuid softnet", softnet: 0755 | os.ModeSetuid, wantSetuid: true, wantReady: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeTart(t, "")
+			softnet := filepath.Join(f.dir, "softnet")
+			if tt.softnet != 0 {
+				if err := os.WriteFile(softnet, []byte("#!/bin/sh\n"), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chmod(softnet, tt.softnet); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// Restrict PATH to the fake so a real softnet can't be found
+			t.Setenv("PATH", f.dir)
+			status, err := f.ServicesStatus()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if status.TartPath != filepath.Join(f.dir, "tart") {
+				t.Errorf("TartPath = %q, want the fake", status.TartPath)
+			}
+			if (status.SoftnetPath != "") != (tt.softnet != 0) || status.SoftnetSetuid != tt.wantSetuid {
+				t.Errorf("status = %+v", status)
+			}
+			if status.Ready() != tt.wantReady {
+				t.Errorf("Ready = %v, want %v", status.Ready(), tt.wantReady)
+			}
+		})
+	}
+}
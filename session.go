@@ -0,0 +1,142 @@
+package tart
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"sync"
+)
+
+// SessionOptions represents the options for starting a Session.
+type SessionOptions struct {
+	// Spec, if it has a source set, is applied with EnsureVM before the VM
+	// is run, creating or cloning it if needed.
+	Spec VMSpec `json:"spec"`
+	// Run holds the options the VM is run with. NoGraphics is typical.
+	Run RunOptions `json:"run"`
+	// SSHPort is the guest's SSH port. Zero uses 22.
+	SSHPort int `json:"sshPort"`
+	// SSHUser is the user commands run as. Empty uses DefaultUser.
+	SSHUser string `json:"sshUser"`
+	// Delete deletes the VM when the session is closed.
+	Delete bool `json:"delete"`
+}
+
+// Session manages a VM for its whole lifecycle: provisioning, running,
+// waiting until it's reachable, running commands in it and tearing it down.
+// Always Close a session once done with it.
+type Session struct {
+	Name   string     `json:"name"`
+	Config VMConfig   `json:"config"`
+	Handle *RunHandle `json:"-"`
+
+	t       *Tart
+	options SessionOptions
+	ip      string
+	user    string
+	once    sync.Once
+	err     error
+}
+
+// NewSession provisions and runs a VM, returning once it's reachable over SSH.
+// It returns an error if any step fails or the context is done first; the
+// VM is stopped (and deleted if requested) in that case.
+func (t *Tart) NewSession(ctx context.Context, name string, options SessionOptions) (s *Session, err error) {
+	s = &Session{Name: name, t: t, options: options}
+	defer func() {
+		if err != nil {
+			s.Close()
+			s = nil
+		}
+	}()
+	if options.Spec.CloneFrom != "" || options.Spec.Create.hasSource() {
+		if _, err := t.EnsureVM(name, options.Spec); err != nil {
+			return s, err
+		}
+	}
+	if s.Config, err = t.Config(name); err != nil {
+		return s, err
+	}
+	s.user = options.SSHUser
+	if s.user == "" {
+		if s.user, err = t.DefaultUser(name); err != nil {
+			return s, err
+		}
+	}
+	if s.Handle, err = t.RunDetached(name, options.Run); err != nil {
+		return s, err
+	}
+	if err := t.WaitForSSH(ctx, name, options.SSHPort); err != nil {
+		return s, err
+	}
+	if s.ip, err = t.IP(name, 0, ""); err != nil {
+		return s, err
+	}
+	return s, nil
+}
+
+// IP returns the VM's IP address.
+func (s *Session) IP() string {
+	return s.ip
+}
+
+// Run runs a shell command in the VM over SSH and returns its stdout.
+// It uses the system ssh client, so authentication relies on the caller's
+// SSH keys and agent; host key checking is disabled because VM host keys
+// change with every image.
+// It returns an error if the command can't be run or exits unsuccessfully.
+func (s *Session) Run(ctx context.Context, command string) ([]byte, error) {
+	port := s.options.SSHPort
+	if port == 0 {
+		port = 22
+	}
+	cmd := exec.CommandContext(ctx, "ssh",
+		"-o", "BatchMode=yes",
+		"-o", "StrictHostKeyChecking=no",
+		"-o", "UserKnownHostsFile=/dev/null",
+		"-o", "LogLevel=ERROR",
+		"-p", strconv.Itoa(port),
+		s.user+"@"+s.ip,
+		command,
+	)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return stdout.Bytes(), fmt.Errorf("failed to run command in VM: %w, stderr: %s", err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
+
+// Stop stops the VM, leaving it in place.
+// It returns an error if the VM can't be stopped.
+func (s *Session) Stop() error {
+	if s.Handle == nil {
+		return nil
+	}
+	return s.Handle.Stop()
+}
+
+// Close stops the VM and, if the session was started with Delete, deletes
+// it. It's safe to call multiple times.
+// It returns an error if the teardown fails.
+func (s *Session) Close() error {
+	s.once.Do(func() {
+		var errs []error
+		if err := s.Stop(); err != nil {
+			errs = append(errs, err)
+		}
+		if s.options.Delete {
+			if exists, err := s.t.Exists(s.Name); err != nil {
+				errs = append(errs, err)
+			} else if exists {
+				errs = append(errs, s.t.Delete(s.Name))
+			}
+		}
+		s.err = errors.Join(errs...)
+	})
+	return s.err
+}
@@ -0,0 +1,95 @@
+package tart
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// listenTCP accepts and closes connections on a local port for the rest of
+// the test, standing in for a guest's SSH server. It returns the port.
+func listenTCP(t *testing.T) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func TestSession(t *testing.T) {
+	port := listenTCP(t)
+	tests := []struct {
+		name    string
+		ip      string
+		delete  bool
+		wantErr error
+		want    []string
+	}{
+		{name: "kept", ip: "echo 127.0.0.1; exit 0", want: []string{"run"}},
+		{name: "deleted", ip: "echo 127.0.0.1; exit 0", delete: true, want: []string{"run", "delete"}},
+		{
+			name:    "torn down on failure",
+			ip:      `echo 'VM "vm" does not exist' >&2; exit 1`,
+			delete:  true,
+			wantErr: ErrVMNotFound,
+			want:    []string{"run", "delete"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeTart(t, `
+if [ "$1" = ip ]; then `+tt.ip+`; fi`+runningScript)
+			f.clk = newFakeClock()
+			f.setList(t, `[{"name":"vm","state":"stopped"}]`)
+			f.addVM(t, "vm", `{"os":"linux"}`)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			s, err := f.NewSession(ctx, "vm", SessionOptions{SSHPort: port, Delete: tt.delete})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewSession = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil {
+				if s.IP() != "127.0.0.1" || s.Config.OS != "linux" {
+					t.Errorf("session IP = %q, OS = %q", s.IP(), s.Config.OS)
+				}
+				if err := s.Close(); err != nil {
+					t.Fatalf("Close: %v", err)
+				}
+				select {
+				case <-s.Handle.Done():
+				default:
+					t.Error("VM still running after Close")
+				}
+				if err := s.Close(); err != nil {
+					t.Fatalf("second Close: %v", err)
+				}
+			}
+			var got []string
+			for _, call := range f.calls(t) {
+				switch call[0] {
+				case "run", "delete":
+					if call[1] != "--help" {
+						got = append(got, call[0])
+					}
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lifecycle calls = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
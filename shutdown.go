@@ -0,0 +1,58 @@
+package tart
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// RegisterShutdownHook installs a handler for SIGINT and SIGTERM that stops
+// every VM this instance started with RunDetached or RunUntilLog, then
+// re-raises the signal so the process exits as it otherwise would have. It's
+// opt-in; call the returned function, or Close, to remove the handler again.
+func (t *Tart) RegisterShutdownHook() (cancel func()) {
+	signals := make(chan os.Signal, 1)
+	quit := make(chan struct{})
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	var once sync.Once
+	cancel = func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			close(quit)
+		})
+	}
+
+	go func() {
+		select {
+		case sig := <-signals:
+			t.stopAll()
+			cancel()
+			if p, err := os.FindProcess(os.Getpid()); err == nil {
+				p.Signal(sig)
+			}
+		case <-quit:
+		}
+	}()
+
+	t.onClose(func() error {
+		cancel()
+		return nil
+	})
+	return cancel
+}
+
+// stopAll stops every VM this instance is tracking, in parallel.
+func (t *Tart) stopAll() {
+	handles := t.RunningHandles()
+	var wg sync.WaitGroup
+	for _, h := range handles {
+		wg.Add(1)
+		go func(h *RunHandle) {
+			defer wg.Done()
+			h.Stop()
+		}(h)
+	}
+	wg.Wait()
+}
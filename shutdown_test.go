@@ -0,0 +1,37 @@
+package tart
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStopAll(t *testing.T) {
+	f := newFakeTart(t, runningScript)
+	f.setList(t, `[{"name":"a","state":"stopped"},{"name":"b","state":"stopped"}]`)
+	var handles []*RunHandle
+	for _, name := range []string{"a", "b"} {
+		h, err := f.RunDetached(name, RunOptions{})
+		if err != nil {
+			t.Fatalf("RunDetached %s: %v", name, err)
+		}
+		handles = append(handles, h)
+	}
+	f.stopAll()
+	for _, h := range handles {
+		select {
+		case <-h.Done():
+		case <-time.After(10 * time.Second):
+			t.Fatalf("VM %s still running after stopAll", h.Name)
+		}
+	}
+}
+
+func TestRegisterShutdownHookCancel(t *testing.T) {
+	f := newFakeTart(t, "")
+	cancel := f.RegisterShutdownHook()
+	cancel()
+	cancel()
+	if err := f.Close(); err != nil {
+		t.Errorf("Close after cancel = %v, want nil", err)
+	}
+}
@@ -0,0 +1,89 @@
+package tart
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// smokeOutputLimit caps how much serial output SmokeTest keeps for its error.
+const smokeOutputLimit = 64 * 1024
+
+// tailBuffer keeps the last limit bytes written to it.
+type tailBuffer struct {
+	mu    sync.Mutex
+	buf   bytes.Buffer
+	limit int
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf.Write(p)
+	if over := b.buf.Len() - b.limit; over > 0 {
+		b.buf.Next(over)
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// SmokeTest checks that a VM boots: it runs the VM without graphics, waits
+// for it to come up and stops it again, all within the timeout.
+// It returns nil only if every step succeeded, or an error including the
+// VM's serial output otherwise.
+func (t *Tart) SmokeTest(name string, opts RunOptions, timeout time.Duration) error {
+	return t.smokeTest(name, opts, timeout, false, 0)
+}
+
+// SmokeTestSSH is like SmokeTest, but also waits for the VM to accept
+// connections on its SSH port before stopping it. A port of zero uses 22.
+func (t *Tart) SmokeTestSSH(name string, opts RunOptions, timeout time.Duration, port int) error {
+	return t.smokeTest(name, opts, timeout, true, port)
+}
+
+func (t *Tart) smokeTest(name string, opts RunOptions, timeout time.Duration, ssh bool, port int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	serial := &tailBuffer{limit: smokeOutputLimit}
+	opts.NoGraphics = true
+	if opts.SerialOutput != nil {
+		opts.SerialOutput = io.MultiWriter(opts.SerialOutput, serial)
+	} else {
+		opts.SerialOutput = serial
+	}
+
+	h, err := t.RunUntilLog(name, readyPattern, timeout, opts)
+	if err != nil {
+		return fmt.Errorf("smoke test of VM %s failed: %w, serial output: %s", name, err, serial.String())
+	}
+	if ssh {
+		if err := t.WaitForSSH(ctx, name, port); err != nil {
+			h.Kill()
+			return fmt.Errorf("smoke test of VM %s failed: %w, serial output: %s", name, err, serial.String())
+		}
+	}
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- h.Stop()
+	}()
+	select {
+	case err := <-stopped:
+		if err != nil {
+			h.Kill()
+			return fmt.Errorf("smoke test of VM %s failed: %w, serial output: %s", name, err, serial.String())
+		}
+		return nil
+	case <-ctx.Done():
+		h.Kill()
+		return fmt.Errorf("smoke test of VM %s failed: VM did not stop in time, serial output: %s", name, serial.String())
+	}
+}
@@ -0,0 +1,69 @@
+package tart
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// smokeScript returns a fake tart script whose run command prints boot, then
+// behaves as given.
+func smokeScript(run string) string {
+	return `
+case "$2" in
+--help) echo "--no-graphics   Run without graphics"; exit 0;;
+esac
+case "$1" in
+run)
+	echo "booting kernel"
+` + run + `;;
+esac`
+}
+
+func TestSmokeTest(t *testing.T) {
+	tests := []struct {
+		name    string
+		run     string
+		timeout time.Duration
+		wantErr string
+	}{
+		{
+			name:    "boots",
+			run:     `echo "VM is up"; i=0; while [ $i -lt 600 ]; do sleep 0.05; i=$((i+1)); done`,
+			timeout: 10 * time.Second,
+		},
+		{
+			name:    "crashes",
+			run:     `echo "kernel panic"; exit 1`,
+			timeout: 10 * time.Second,
+			wantErr: "kernel panic",
+		},
+		{
+			name:    "never ready",
+			run:     `i=0; while [ $i -lt 600 ]; do sleep 0.05; i=$((i+1)); done`,
+			timeout: 200 * time.Millisecond,
+			wantErr: "booting kernel",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeTart(t, smokeScript(tt.run))
+			f.setList(t, `[{"name":"vm","state":"stopped"}]`)
+			err := f.SmokeTest("vm", RunOptions{}, tt.timeout)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("SmokeTest: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("SmokeTest = %v, want an error containing %q", err, tt.wantErr)
+			}
+			runs := f.commandCalls(t, "run")
+			if len(runs) != 2 || runs[1][1] != "--no-graphics" {
+				t.Errorf("run calls = %q, want the help probe and a run without graphics", runs)
+			}
+			if len(f.RunningHandles()) != 0 {
+				t.Error("VM is still running after SmokeTest")
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package tart
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Snapshot represents a saved point-in-time copy of a VM.
+type Snapshot struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	Size      int64     `json:"size"`
+}
+
+// Tart releases to date have no snapshot support, so the snapshot methods
+// check for a `tart snapshot` command first and return an error wrapping
+// ErrUnsupported when it's missing.
+
+// Snapshots lists a VM's snapshots.
+// It returns an error wrapping ErrUnsupported if the installed Tart has no
+// snapshot support, or an error if the listing process fails.
+func (t *Tart) Snapshots(name string) ([]Snapshot, error) {
+	if err := t.requireCommand("snapshot"); err != nil {
+		return nil, err
+	}
+	output, err := t.run("snapshot", "list", name, "--format", "json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w, output: %s", err, string(output))
+	}
+	var snapshots []Snapshot
+	if err := json.Unmarshal(output, &snapshots); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshots: %w", err)
+	}
+	return snapshots, nil
+}
+
+// CreateSnapshot creates a snapshot of a VM.
+// It returns an error wrapping ErrUnsupported if the installed Tart has no
+// snapshot support, or an error if the snapshot process fails.
+func (t *Tart) CreateSnapshot(name string, id string) error {
+	return t.snapshotCommand("create", name, id)
+}
+
+// RestoreSnapshot restores a VM to a snapshot.
+// It returns an error wrapping ErrUnsupported if the installed Tart has no
+// snapshot support, or an error if the restore process fails.
+func (t *Tart) RestoreSnapshot(name string, id string) error {
+	return t.snapshotCommand("restore", name, id)
+}
+
+// DeleteSnapshot deletes a VM's snapshot.
+// It returns an error wrapping ErrUnsupported if the installed Tart has no
+// snapshot support, or an error if the deletion process fails.
+func (t *Tart) DeleteSnapshot(name string, id string) error {
+	return t.snapshotCommand("delete", name, id)
+}
+
+// snapshotCommand runs a `tart snapshot` subcommand.
+func (t *Tart) snapshotCommand(verb string, name string, id string) error {
+	if err := t.requireCommand("snapshot"); err != nil {
+		return err
+	}
+	output, err := t.run("snapshot", verb, name, id)
+	if err != nil {
+		return fmt.Errorf("failed to %s snapshot: %w, output: %s", verb, err, string(output))
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package tart
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// helpScript answers `tart --help` with the given subcommands section and
+// `tart snapshot list` with one snapshot.
+func helpScript(subcommands string) string {
+	return `
+case "$1" in
+--help) printf 'USAGE: tart <subcommand>\n\nSUBCOMMANDS:\n` + subcommands + `\n  See '\''tart help <subcommand>'\'' for detailed help.\n'; exit 0;;
+--version) echo 2.20.0; exit 0;;
+snapshot)
+	if [ "$2" = list ]; then echo '[{"id":"s1","createdAt":"2026-01-02T03:04:05Z","size":42}]'; fi
+	exit 0;;
+esac`
+}
+
+func TestSnapshotsUnsupported(t *testing.T) {
+	f := newFakeTart(t, helpScript(`  create    Create a VM\n  run       Run a VM`))
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"list", func() error { _, err := f.Snapshots("vm"); return err }},
+		{"create", func() error { return f.CreateSnapshot("vm", "s1") }},
+		{"restore", func() error { return f.RestoreSnapshot("vm", "s1") }},
+		{"delete", func() error { return f.DeleteSnapshot("vm", "s1") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !errors.Is(err, ErrUnsupported) {
+				t.Errorf("error = %v, want ErrUnsupported", err)
+			}
+		})
+	}
+	if calls := f.commandCalls(t, "snapshot"); len(calls) != 0 {
+		t.Errorf("snapshot was run without support: %q", calls)
+	}
+}
+
+func TestSnapshotsSupported(t *testing.T) {
+	f := newFakeTart(t, helpScript(`  run       Run a VM\n  snapshot  Manage snapshots`))
+	snapshots, err := f.Snapshots("vm")
+	if err != nil {
+		t.Fatalf("Snapshots: %v", err)
+	}
+	if len(snapshots) != 1 || snapshots[0].ID != "s1" || snapshots[0].Size != 42 || snapshots[0].CreatedAt.Year() != 2026 {
+		t.Errorf("Snapshots = %+v", snapshots)
+	}
+	if err := f.CreateSnapshot("vm", "s2"); err != nil {
+		t.Fatalf("CreateSnapshot: %v", err)
+	}
+	want := [][]string{
+		{"snapshot", "list", "vm", "--format", "json"},
+		{"snapshot", "create", "vm", "s2"},
+	}
+	if got := f.commandCalls(t, "snapshot"); !reflect.DeepEqual(got, want) {
+		t.Errorf("snapshot calls = %q, want %q", got, want)
+	}
+}
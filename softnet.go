@@ -0,0 +1,77 @@
+package tart
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// SoftnetConfig configures softnet, Tart's userspace NAT networking, which
+// isolates a VM from the host's local networks and from other VMs.
+type SoftnetConfig struct {
+	// AllowAll lifts the isolation entirely, letting the VM reach any
+	// network the host can. It can't be combined with Block.
+	AllowAll bool `json:"allowAll"`
+	// Allow lists the networks, as CIDRs, the VM may reach despite the
+	// isolation.
+	Allow []string `json:"allow"`
+	// Block lists the networks, as CIDRs, the VM may not reach.
+	Block []string `json:"block"`
+	// Expose forwards ports on the host to the VM.
+	Expose []PortForward `json:"expose"`
+}
+
+// PortForward forwards a TCP port on the host to a port in the VM.
+type PortForward struct {
+	HostPort  int `json:"hostPort"`
+	GuestPort int `json:"guestPort"`
+}
+
+// allow returns the allow-list, including the catch-all for AllowAll.
+func (c SoftnetConfig) allow() []string {
+	if c.AllowAll {
+		return append([]string{"0.0.0.0/0"}, c.Allow...)
+	}
+	return c.Allow
+}
+
+// expose renders the port forwards as the value of --net-softnet-expose.
+func (c SoftnetConfig) expose() string {
+	rules := make([]string, len(c.Expose))
+	for i, p := range c.Expose {
+		rules[i] = strconv.Itoa(p.HostPort) + ":" + strconv.Itoa(p.GuestPort)
+	}
+	return strings.Join(rules, ",")
+}
+
+// validate returns the problems with the configuration.
+func (c SoftnetConfig) validate() []error {
+	var problems []error
+	if c.AllowAll && len(c.Block) > 0 {
+		problems = append(problems, errors.New("softnet AllowAll can't be combined with Block"))
+	}
+	for _, list := range []struct {
+		name  string
+		cidrs []string
+	}{{"allow", c.Allow}, {"block", c.Block}} {
+		for _, cidr := range list.cidrs {
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				problems = append(problems, fmt.Errorf("invalid softnet %s CIDR %q: %w", list.name, cidr, err))
+			}
+		}
+	}
+	hostPorts := map[int]bool{}
+	for _, p := range c.Expose {
+		if p.HostPort < 1 || p.HostPort > 65535 || p.GuestPort < 1 || p.GuestPort > 65535 {
+			problems = append(problems, fmt.Errorf("invalid softnet port forward %d:%d: ports must be between 1 and 65535", p.HostPort, p.GuestPort))
+			continue
+		}
+		if hostPorts[p.HostPort] {
+			problems = append(problems, fmt.Errorf("invalid softnet port forward %d:%d: host port %d is forwarded twice", p.HostPort, p.GuestPort, p.HostPort))
+		}
+		hostPorts[p.HostPort] = true
+	}
+	return problems
+}
@@ -0,0 +1,41 @@
+package tart
+
+import "testing"
+
+func TestRunArgsSoftnet(t *testing.T) {
+	testRunArgs(t, []runArgsTest{
+		{name: "plain", options: RunOptions{NetSoftnet: true}, want: []string{"run", "--net-softnet", "vm"}},
+		{
+			name:    "allow all",
+			options: RunOptions{Softnet: &SoftnetConfig{AllowAll: true}},
+			want:    []string{"run", "--net-softnet", "--net-softnet-allow", "0.0.0.0/0", "vm"},
+		},
+		{
+			name: "combined allow-lists",
+			options: RunOptions{
+				NetSoftnetAllow:      "10.0.0.0/8",
+				NetSoftnetAllowCIDRs: []string{"192.168.1.0/24"},
+				Softnet:              &SoftnetConfig{Allow: []string{"172.16.0.0/12"}},
+			},
+			want: []string{"run", "--net-softnet", "--net-softnet-allow", "10.0.0.0/8,192.168.1.0/24,172.16.0.0/12", "vm"},
+		},
+		{
+			name: "block and expose",
+			options: RunOptions{Softnet: &SoftnetConfig{
+				Block:  []string{"10.0.0.0/8", "192.168.0.0/16"},
+				Expose: []PortForward{{HostPort: 2222, GuestPort: 22}, {HostPort: 8080, GuestPort: 80}},
+			}},
+			want: []string{"run", "--net-softnet", "--net-softnet-block", "10.0.0.0/8,192.168.0.0/16", "--net-softnet-expose", "2222:22,8080:80", "vm"},
+		},
+		{name: "allow all with block", options: RunOptions{Softnet: &SoftnetConfig{AllowAll: true, Block: []string{"10.0.0.0/8"}}}, wantErr: true},
+		{name: "invalid block CIDR", options: RunOptions{Softnet: &SoftnetConfig{Block: []string{"10.0.0.0"}}}, wantErr: true},
+		{name: "invalid allow CIDR", options: RunOptions{NetSoftnetAllowCIDRs: []string{"nope"}}, wantErr: true},
+		{name: "port out of range", options: RunOptions{Softnet: &SoftnetConfig{Expose: []PortForward{{HostPort: 70000, GuestPort: 22}}}}, wantErr: true},
+		{
+			name:    "host port forwarded twice",
+			options: RunOptions{Softnet: &SoftnetConfig{Expose: []PortForward{{HostPort: 2222, GuestPort: 22}, {HostPort: 2222, GuestPort: 23}}}},
+			wantErr: true,
+		},
+		{name: "with bridged", options: RunOptions{NetBridged: "en0", Softnet: &SoftnetConfig{}}, wantErr: true},
+	})
+}
@@ -0,0 +1,126 @@
+package tart
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// configSpecVersion is the version of the spec file format written by
+// ExportSpec.
+const configSpecVersion = 1
+
+// ConfigSpec is the portable description of a VM's configuration written by
+// ExportSpec and read by ImportSpec. It captures the configuration intent
+// only, not the disk image, so it can be kept under version control.
+type ConfigSpec struct {
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+	// CloneFrom, if set, is the local VM or remote image ImportSpec clones
+	// when the VM doesn't exist. ExportSpec leaves it empty since Tart
+	// doesn't record where a VM came from.
+	CloneFrom string            `json:"cloneFrom,omitempty"`
+	Config    VMConfig          `json:"config"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+// ExportSpec writes a VM's configuration and metadata to a JSON spec file at
+// path.
+// It returns an error if the VM's configuration or metadata can't be read or
+// the file can't be written.
+func (t *Tart) ExportSpec(name string, path string) error {
+	config, err := t.Config(name)
+	if err != nil {
+		return err
+	}
+	metadata, err := t.GetMetadata(name)
+	if err != nil {
+		return err
+	}
+	spec := ConfigSpec{
+		Version:  configSpecVersion,
+		Name:     name,
+		Config:   config,
+		Metadata: metadata,
+	}
+	data, err := json.MarshalIndent(spec, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode VM spec: %w", err)
+	}
+	if err := t.writeFile(t.resolvePath(path), append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write VM spec: %w", err)
+	}
+	return nil
+}
+
+// readConfigSpec reads and validates a spec file, rejecting unknown fields.
+func readConfigSpec(path string) (ConfigSpec, error) {
+	var spec ConfigSpec
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return spec, fmt.Errorf("failed to read VM spec: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&spec); err != nil {
+		return spec, fmt.Errorf("invalid VM spec %s: %w", path, err)
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return spec, fmt.Errorf("invalid VM spec %s: unexpected data after the spec", path)
+	}
+	if spec.Version != configSpecVersion {
+		return spec, fmt.Errorf("invalid VM spec %s: unsupported version %d", path, spec.Version)
+	}
+	if spec.Name == "" {
+		return spec, fmt.Errorf("invalid VM spec %s: name is required", path)
+	}
+	return spec, nil
+}
+
+// ImportSpec applies a spec file written by ExportSpec. A VM that doesn't
+// exist is cloned from the spec's CloneFrom source; the configuration is
+// then applied as with EnsureVM and the metadata, if any, replaces the VM's.
+// The spec's OS, architecture and minimums are informational, and so is its
+// MAC address unless the VM already exists: a VM cloned from the spec keeps
+// the address it was cloned with, so it can't collide with the VM the spec
+// was exported from.
+// It returns the VM's name, and an error if the spec is invalid, the VM is
+// missing and the spec has no source, or applying it fails.
+func (t *Tart) ImportSpec(path string) (string, error) {
+	spec, err := readConfigSpec(t.resolvePath(path))
+	if err != nil {
+		return "", err
+	}
+	name := spec.Name
+	exists, err := t.Exists(name)
+	if err != nil {
+		return name, err
+	}
+	if !exists && spec.CloneFrom == "" {
+		return name, fmt.Errorf("%w: %s, and the spec has no cloneFrom source", ErrVMNotFound, name)
+	}
+	config := spec.Config
+	config.Version, config.OS, config.Arch = 0, "", ""
+	config.CPUCountMin, config.MemorySizeMin = 0, 0
+	if !exists {
+		config.MACAddress = ""
+	}
+	source := spec.CloneFrom
+	if source == "" {
+		// The VM exists, so the source is never used; it only satisfies the
+		// spec's validation.
+		source = name
+	}
+	if _, err := t.EnsureVM(name, VMSpec{CloneFrom: source, Config: config}); err != nil {
+		return name, err
+	}
+	if len(spec.Metadata) > 0 {
+		if err := t.SetMetadata(name, spec.Metadata); err != nil {
+			return name, err
+		}
+	}
+	return name, nil
+}
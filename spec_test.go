@@ -0,0 +1,123 @@
+package tart
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeSpec writes a ConfigSpec to a file in a temporary directory.
+func writeSpec(t *testing.T, spec ConfigSpec) string {
+	t.Helper()
+	data, err := json.Marshal(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "spec.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestImportSpecCloneIgnoresMAC(t *testing.T) {
+	f := newFakeTart(t, `
+if [ "$1" = clone ]; then
+	mkdir -p "$TART_HOME/vms/$3"
+	echo '{"macAddress":"0a:00:00:00:00:01"}' > "$TART_HOME/vms/$3/config.json"
+	exit 0
+fi`)
+	var config VMConfig
+	config.MACAddress = "0a:00:00:00:00:99"
+	path := writeSpec(t, ConfigSpec{Version: configSpecVersion, Name: "copy", CloneFrom: "base", Config: config})
+	if _, err := f.ImportSpec(path); err != nil {
+		t.Fatalf("ImportSpec: %v", err)
+	}
+	got, err := f.Config("copy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.MACAddress != "0a:00:00:00:00:01" {
+		t.Errorf("clone MAC = %s, want the address it was cloned with", got.MACAddress)
+	}
+}
+
+func TestImportSpecExistingAppliesMAC(t *testing.T) {
+	f := newFakeTart(t, "")
+	f.addVM(t, "vm", `{"macAddress":"0a:00:00:00:00:01"}`)
+	f.setList(t, `[{"name":"vm","state":"stopped","source":"local"}]`)
+	var config VMConfig
+	config.MACAddress = "0a:00:00:00:00:99"
+	path := writeSpec(t, ConfigSpec{Version: configSpecVersion, Name: "vm", Config: config})
+	if _, err := f.ImportSpec(path); err != nil {
+		t.Fatalf("ImportSpec: %v", err)
+	}
+	got, err := f.Config("vm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.MACAddress != "0a:00:00:00:00:99" {
+		t.Errorf("MAC = %s, want the spec's", got.MACAddress)
+	}
+}
+
+func TestExportSpec(t *testing.T) {
+	f := newFakeTart(t, "")
+	f.addVM(t, "vm", `{"os":"darwin","cpuCount":4,"memorySize":8589934592}`)
+	if err := f.metadata().Set("vm", map[string]string{"owner": "ci"}); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "vm.json")
+	if err := f.ExportSpec("vm", path); err != nil {
+		t.Fatalf("ExportSpec: %v", err)
+	}
+	spec, err := readConfigSpec(path)
+	if err != nil {
+		t.Fatalf("readConfigSpec: %v", err)
+	}
+	if spec.Name != "vm" || spec.Config.OS != "darwin" || spec.Config.CPUCount != 4 || spec.Config.MemorySize != 8192 {
+		t.Errorf("spec = %+v", spec)
+	}
+	if spec.Metadata["owner"] != "ci" {
+		t.Errorf("spec metadata = %v, want owner=ci", spec.Metadata)
+	}
+}
+
+func TestReadConfigSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{name: "valid", data: `{"version":1,"name":"vm","config":{"cpuCount":4}}`},
+		{name: "unknown field", data: `{"version":1,"name":"vm","disk":"big"}`, wantErr: true},
+		{name: "unsupported version", data: `{"version":2,"name":"vm"}`, wantErr: true},
+		{name: "no name", data: `{"version":1}`, wantErr: true},
+		{name: "trailing data", data: `{"version":1,"name":"vm"} {}`, wantErr: true},
+		{name: "not json", data: `name: vm`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "spec.json")
+			if err := os.WriteFile(path, []byte(tt.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := readConfigSpec(path); (err != nil) != tt.wantErr {
+				t.Errorf("readConfigSpec = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestImportSpecMissingWithoutSource(t *testing.T) {
+	f := newFakeTart(t, "")
+	path := writeSpec(t, ConfigSpec{Version: configSpecVersion, Name: "vm"})
+	if _, err := f.ImportSpec(path); !errors.Is(err, ErrVMNotFound) {
+		t.Errorf("ImportSpec = %v, want ErrVMNotFound", err)
+	}
+	if clones := f.commandCalls(t, "clone"); len(clones) != 0 {
+		t.Errorf("clone calls = %q, want none", clones)
+	}
+}
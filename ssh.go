@@ -0,0 +1,69 @@
+package tart
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// DefaultSSHUser is the username used by the Cirrus Labs macOS and Linux
+// images, and the fallback when a VM's OS has no known convention.
+const DefaultSSHUser = "admin"
+
+// defaultUsers maps a guest OS to its conventional SSH username.
+var defaultUsers = map[string]string{
+	"darwin": DefaultSSHUser,
+	"linux":  DefaultSSHUser,
+}
+
+// DefaultUser returns the conventional SSH username for a VM based on its OS.
+// Entries in Tart.DefaultUsers take precedence over the built-in conventions.
+// When the OS is unknown, DefaultSSHUser is returned on the assumption that
+// the image follows the Cirrus Labs convention.
+// It returns an error if the VM's configuration can't be read.
+func (t *Tart) DefaultUser(name string) (string, error) {
+	config, err := t.Config(name)
+	if err != nil {
+		return "", fmt.Errorf("failed to get VM OS: %w", err)
+	}
+	if user, ok := t.DefaultUsers[config.OS]; ok && user != "" {
+		return user, nil
+	}
+	if user, ok := defaultUsers[config.OS]; ok {
+		return user, nil
+	}
+	return DefaultSSHUser, nil
+}
+
+// WaitForSSH waits until a VM accepts TCP connections on its SSH port,
+// resolving its IP address and dialing the port until both succeed. It only
+// checks reachability and doesn't attempt to authenticate. A port of zero
+// uses 22. Attempts are spaced out with the instance's PollBackoff.
+// It returns an error wrapping ErrVMNotFound as soon as the VM turns out not
+// to exist, or an error if the context is done before the port is reachable.
+func (t *Tart) WaitForSSH(ctx context.Context, name string, port int) error {
+	if port == 0 {
+		port = 22
+	}
+	backoff := t.pollBackoff()
+	var dialer net.Dialer
+	for {
+		ip, err := t.IP(name, 0, "")
+		if errors.Is(err, ErrVMNotFound) {
+			return err
+		}
+		if err == nil && ip != "" {
+			var conn net.Conn
+			conn, err = dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip, strconv.Itoa(port)))
+			if err == nil {
+				conn.Close()
+				return nil
+			}
+		}
+		if waitErr := backoff.Wait(ctx); waitErr != nil {
+			return fmt.Errorf("VM %s is not reachable over SSH: %w (last error: %v)", name, waitErr, err)
+		}
+	}
+}
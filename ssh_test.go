@@ -0,0 +1,99 @@
+package tart
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestWaitForSSH(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	tests := []struct {
+		name    string
+		script  string
+		wantErr error
+		wantIPs int
+	}{
+		{
+			name: "reachable after boot",
+			script: `
+if [ "$1" = ip ]; then
+	if [ -f "$FAKE_TART_DIR/booted" ]; then echo 127.0.0.1; exit 0; fi
+	touch "$FAKE_TART_DIR/booted"
+	echo "no IP address found" >&2; exit 1
+fi`,
+			wantIPs: 2,
+		},
+		{
+			name:    "missing VM",
+			script:  `[ "$1" = ip ] && { echo "VM \"vm\" does not exist" >&2; exit 1; }`,
+			wantErr: ErrVMNotFound,
+			wantIPs: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeTart(t, tt.script)
+			f.clk = newFakeClock()
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			err := f.WaitForSSH(ctx, "vm", port)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("WaitForSSH = %v, want %v", err, tt.wantErr)
+			}
+			if ips := f.commandCalls(t, "ip"); len(ips) != tt.wantIPs {
+				t.Errorf("got %d IP lookups, want %d", len(ips), tt.wantIPs)
+			}
+		})
+	}
+}
+
+func TestDefaultUser(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    string
+		overrides map[string]string
+		want      string
+		wantErr   bool
+	}{
+		{name: "darwin", config: `{"os":"darwin"}`, want: DefaultSSHUser},
+		{name: "linux", config: `{"os":"linux"}`, want: DefaultSSHUser},
+		{name: "override", config: `{"os":"linux"}`, overrides: map[string]string{"linux": "ubuntu"}, want: "ubuntu"},
+		{name: "empty override", config: `{"os":"linux"}`, overrides: map[string]string{"linux": ""}, want: DefaultSSHUser},
+		{name: "unknown OS", config: `{"os":"plan9"}`, want: DefaultSSHUser},
+		{name: "missing VM", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeTart(t, "")
+			f.DefaultUsers = tt.overrides
+			if tt.config != "" {
+				f.addVM(t, "vm", tt.config)
+			}
+			got, err := f.DefaultUser("vm")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DefaultUser error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("DefaultUser = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
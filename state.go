@@ -1,9 +1,13 @@
 package tart
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"strings"
+	"time"
 )
 
 // Constants representing the options for a VM source.
@@ -12,6 +16,29 @@ const (
 	SourceRemote = "remote"
 )
 
+// Constants representing the states of a VM.
+const (
+	StateRunning   = "running"
+	StateStopped   = "stopped"
+	StateSuspended = "suspended"
+	// StatePaused is reported for a VM whose vCPUs are frozen in memory by
+	// Pause, by Tart releases that support it.
+	StatePaused = "paused"
+)
+
+// Constants representing the resolvers used to find a VM's IP address.
+const (
+	// ResolverDHCP looks up the VM's MAC address in the host's DHCP leases.
+	ResolverDHCP = "dhcp"
+	// ResolverARP looks up the VM's MAC address in the host's ARP cache,
+	// which is needed for bridged networking.
+	ResolverARP = "arp"
+	// ResolverAgent asks the Tart guest agent running inside the VM. It's the
+	// most reliable right after boot, but the guest must have the agent
+	// installed and running.
+	ResolverAgent = "agent"
+)
+
 // ListOptions represents the options for listing VMs.
 type ListOptions struct {
 	Source *string `json:"source,omitempty"`
@@ -61,6 +88,46 @@ func (t *Tart) List(config ListOptions) ([]VMState, error) {
 	return vms, nil
 }
 
+// ListNames lists the names of VMs, honoring the source filter.
+// It returns an error if the listing process fails.
+func (t *Tart) ListNames(config ListOptions) ([]string, error) {
+	vms, err := t.List(config)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(vms))
+	for i, vm := range vms {
+		names[i] = vm.Name
+	}
+	return names, nil
+}
+
+// ListAll lists both local and remote VMs, labelling each with its source.
+// If only the remote listing fails, for example because the registry needs
+// a login, the local VMs are returned together with an error describing the
+// failure.
+// It returns an error if the listing process fails.
+func (t *Tart) ListAll() ([]VMState, error) {
+	var all []VMState
+	for _, source := range []string{SourceLocal, SourceRemote} {
+		source := source
+		vms, err := t.List(ListOptions{Source: &source})
+		if err != nil {
+			if source == SourceRemote {
+				return all, fmt.Errorf("failed to list remote VMs: %w", err)
+			}
+			return nil, err
+		}
+		for _, vm := range vms {
+			if vm.Source == "" {
+				vm.Source = source
+			}
+			all = append(all, vm)
+		}
+	}
+	return all, nil
+}
+
 // State gets the state of a VM.
 // It returns a VMState struct and an error if the state retrieval process fails.
 func (t *Tart) State(name string) (VMState, error) {
@@ -78,9 +145,35 @@ func (t *Tart) State(name string) (VMState, error) {
 	return ret, nil
 }
 
+// States retrieves the states of several VMs with a single listing.
+// VMs that don't exist are absent from the returned map.
+// It returns an error if the listing fails.
+func (t *Tart) States(names []string) (map[string]VMState, error) {
+	vms, err := t.List(ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get VM states: %w", err)
+	}
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[name] = true
+	}
+	ret := make(map[string]VMState, len(names))
+	for _, s := range vms {
+		if wanted[s.Name] {
+			ret[s.Name] = s
+		}
+	}
+	return ret, nil
+}
+
 // IP retrieves a VM's IP address.
-// It returns the IP address as a string and an error if the retrieval process fails.
+// It returns the IP address as a string, an error wrapping ErrIPNotReady if
+// the VM has no IP address yet, one wrapping ErrVMNotFound if the VM doesn't
+// exist, and an error if the retrieval process otherwise fails.
 func (t *Tart) IP(name string, wait int, resolver string) (string, error) {
+	if resolver != "" && resolver != ResolverDHCP && resolver != ResolverARP && resolver != ResolverAgent {
+		return "", fmt.Errorf("invalid resolver: %s", resolver)
+	}
 	args := []string{"ip", name}
 	if wait > 0 {
 		args = append(args, "--wait", fmt.Sprintf("%d", wait))
@@ -90,11 +183,68 @@ func (t *Tart) IP(name string, wait int, resolver string) (string, error) {
 	}
 	output, err := t.run(args...)
 	if err != nil {
+		stderr := commandStderr(err)
+		switch {
+		case strings.Contains(stderr, "does not exist"):
+			return "", fmt.Errorf("%w: %s", ErrVMNotFound, name)
+		case strings.Contains(stderr, "no IP address found"):
+			return "", fmt.Errorf("%w: %s", ErrIPNotReady, name)
+		}
+		if resolver == ResolverAgent {
+			return "", fmt.Errorf("failed to get VM IP from the guest agent (is it installed and running in the VM?): %w, output: %s", err, string(output))
+		}
 		return "", fmt.Errorf("failed to get VM IP: %w, output: %s", err, string(output))
 	}
 	return strings.TrimSpace(string(output)), nil
 }
 
+// defaultIPRetryDelay is the delay between IP lookups when IPOptions leaves
+// RetryDelay at zero.
+const defaultIPRetryDelay = time.Second
+
+// IPOptions represents the options for retrieving a VM's IP address.
+type IPOptions struct {
+	Wait     int    `json:"wait"`
+	Resolver string `json:"resolver"`
+	// Retries is how many more times the lookup is made when it returns an
+	// empty or invalid address or the VM has no address yet, as can happen
+	// right after boot. Other errors aren't retried.
+	Retries int `json:"retries"`
+	// RetryDelay is the delay between attempts. Zero means one second.
+	RetryDelay time.Duration `json:"retryDelay"`
+}
+
+// IPWithOptions retrieves a VM's IP address like IP, retrying transient
+// failures as the options specify.
+// It returns the first valid address, an error wrapping ErrIPNotReady if
+// every attempt found no valid address, or the first error that isn't
+// transient.
+func (t *Tart) IPWithOptions(name string, options IPOptions) (string, error) {
+	delay := options.RetryDelay
+	if delay <= 0 {
+		delay = defaultIPRetryDelay
+	}
+	for attempt := 0; ; attempt++ {
+		ip, err := t.IP(name, options.Wait, options.Resolver)
+		if err == nil {
+			if net.ParseIP(ip) != nil {
+				return ip, nil
+			}
+			err = fmt.Errorf("%w: %s returned invalid address %q", ErrIPNotReady, name, ip)
+		}
+		if !errors.Is(err, ErrIPNotReady) || attempt >= options.Retries {
+			return "", err
+		}
+		t.clock().Sleep(delay)
+	}
+}
+
+// IPViaAgent retrieves a VM's IP address from the Tart guest agent.
+// It returns an error if the agent isn't available in the VM or the retrieval process fails.
+func (t *Tart) IPViaAgent(name string, wait int) (string, error) {
+	return t.IP(name, wait, ResolverAgent)
+}
+
 // Exists checks if a VM exists
 func (t *Tart) Exists(name string) (bool, error) {
 	localVMs, err := t.List(ListOptions{})
@@ -135,3 +285,70 @@ func (t *Tart) Suspended(name string) (bool, error) {
 	}
 	return s.State == "suspended", nil
 }
+
+// Paused checks if a VM is paused.
+// It returns true if the VM is paused, false otherwise, and an error if the state retrieval process fails.
+func (t *Tart) Paused(name string) (bool, error) {
+	s, err := t.State(name)
+	if err != nil {
+		return false, fmt.Errorf("failed to get VM state: %w", err)
+	}
+	return s.State == StatePaused, nil
+}
+
+// WaitForState waits until a VM reaches the given state, polling with the
+// instance's PollBackoff.
+// It returns an error if the state can't be retrieved or the context is done
+// before the VM reaches the state.
+func (t *Tart) WaitForState(ctx context.Context, name string, state string) error {
+	backoff := t.pollBackoff()
+	for {
+		s, err := t.State(name)
+		if err != nil {
+			return err
+		}
+		if s.State == state {
+			return nil
+		}
+		if err := backoff.Wait(ctx); err != nil {
+			return fmt.Errorf("VM %s did not reach state %s: %w", name, state, err)
+		}
+	}
+}
+
+// WaitForStates waits until every named VM is in the given state, checking
+// them all with a single listing per poll, for example to wait for a whole
+// cluster to boot. A pollInterval of zero or less polls with the instance's
+// PollBackoff instead.
+// It returns an error naming the VMs that hadn't reached the state when the
+// context was done, or an error if the states can't be retrieved.
+func (t *Tart) WaitForStates(ctx context.Context, names []string, state string, pollInterval time.Duration) error {
+	backoff := t.pollBackoff()
+	for {
+		states, err := t.States(names)
+		if err != nil {
+			return err
+		}
+		var pending []string
+		for _, name := range names {
+			if states[name].State != state {
+				pending = append(pending, name)
+			}
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+		if pollInterval > 0 {
+			select {
+			case <-ctx.Done():
+				err = ctx.Err()
+			case <-t.clock().After(pollInterval):
+			}
+		} else {
+			err = backoff.Wait(ctx)
+		}
+		if err != nil {
+			return fmt.Errorf("VMs %s did not reach state %s: %w", strings.Join(pending, ", "), state, err)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package tart
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWaitForStatesListsOncePerPoll(t *testing.T) {
+	tests := []struct {
+		name         string
+		pollInterval time.Duration
+	}{
+		{name: "fixed interval", pollInterval: time.Second},
+		{name: "backoff", pollInterval: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeTart(t, "")
+			f.setList(t, `[{"name":"a","state":"stopped"},{"name":"b","state":"stopped"},{"name":"c","state":"running"}]`)
+			clk := newFakeClock()
+			clk.onWait = func(time.Duration) {
+				switch len(f.commandCalls(t, "list")) {
+				case 1:
+					f.setList(t, `[{"name":"a","state":"running"},{"name":"b","state":"stopped"},{"name":"c","state":"running"}]`)
+				case 2:
+					f.setList(t, `[{"name":"a","state":"running"},{"name":"b","state":"running"},{"name":"c","state":"running"}]`)
+				}
+			}
+			f.clk = clk
+			if err := f.WaitForStates(context.Background(), []string{"a", "b", "c"}, StateRunning, tt.pollInterval); err != nil {
+				t.Fatalf("WaitForStates: %v", err)
+			}
+			if lists := f.commandCalls(t, "list"); len(lists) != 3 {
+				t.Errorf("got %d list calls, want 3", len(lists))
+			}
+			if waits := clk.recordedWaits(); len(waits) != 2 {
+				t.Errorf("got %d waits, want 2", len(waits))
+			}
+		})
+	}
+}
+
+func TestIPResolver(t *testing.T) {
+	tests := []struct {
+		name     string
+		resolver string
+		want     []string
+		wantErr  bool
+	}{
+		{name: "default", want: []string{"ip", "vm", "--wait", "5"}},
+		{name: "arp", resolver: ResolverARP, want: []string{"ip", "vm", "--wait", "5", "--resolver", "arp"}},
+		{name: "agent", resolver: ResolverAgent, want: []string{"ip", "vm", "--wait", "5", "--resolver", "agent"}},
+		{name: "invalid", resolver: "mdns", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeTart(t, `
+if [ "$1" = ip ]; then echo 192.168.64.2; exit 0; fi`)
+			ip, err := f.IP("vm", 5, tt.resolver)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("IP error = %v, wantErr %v", err, tt.wantErr)
+			}
+			calls := f.commandCalls(t, "ip")
+			if tt.wantErr {
+				if len(calls) != 0 {
+					t.Errorf("ip calls = %q, want none", calls)
+				}
+				return
+			}
+			if ip != "192.168.64.2" {
+				t.Errorf("IP = %q, want 192.168.64.2", ip)
+			}
+			if len(calls) != 1 || !reflect.DeepEqual(calls[0], tt.want) {
+				t.Errorf("ip calls = %q, want %q", calls, tt.want)
+			}
+		})
+	}
+}
+
+func TestIPViaAgentWithoutAgent(t *testing.T) {
+	f := newFakeTart(t, `
+if [ "$1" = ip ]; then echo "guest agent is not reachable" >&2; exit 1; fi`)
+	_, err := f.IPViaAgent("vm", 0)
+	if err == nil || !strings.Contains(err.Error(), "guest agent (is it installed and running in the VM?)") {
+		t.Errorf("IPViaAgent = %v, want an error about the guest agent", err)
+	}
+	if calls := f.commandCalls(t, "ip"); len(calls) != 1 || calls[0][len(calls[0])-1] != ResolverAgent {
+		t.Errorf("ip calls = %q, want the agent resolver", calls)
+	}
+}
+
+func TestIPErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		stderr string
+		is     error
+	}{
+		{name: "no lease", stderr: "no IP address found, is your VM running?", is: ErrIPNotReady},
+		{name: "missing VM", stderr: "the specified VM \"vm\" does not exist", is: ErrVMNotFound},
+		{name: "other failure", stderr: "permission denied"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeTart(t, `
+if [ "$1" = ip ]; then echo '`+tt.stderr+`' >&2; exit 1; fi`)
+			_, err := f.IP("vm", 0, "")
+			if err == nil {
+				t.Fatal("IP succeeded, want an error")
+			}
+			for _, sentinel := range []error{ErrIPNotReady, ErrVMNotFound} {
+				if errors.Is(err, sentinel) != (sentinel == tt.is) {
+					t.Errorf("IP = %v, errors.Is(%v) = %v", err, sentinel, !(sentinel == tt.is))
+				}
+			}
+		})
+	}
+}
+
+func TestIPWithOptionsRetries(t *testing.T) {
+	// The fake answers with an invalid address, then no lease, then a valid
+	// address, unless the VM is missing
+	script := `
+if [ "$1" = ip ]; then
+	[ "$2" = missing ] && { echo "the specified VM \"missing\" does not exist" >&2; exit 1; }
+	n=$(cat "$FAKE_TART_DIR/ips" 2>/dev/null || echo 0)
+	echo $((n+1)) > "$FAKE_TART_DIR/ips"
+	case $n in
+	0) echo "0.0.0"; exit 0;;
+	1) echo "no IP address found, is your VM running?" >&2; exit 1;;
+	*) echo "192.168.64.5"; exit 0;;
+	esac
+fi`
+	tests := []struct {
+		name      string
+		vm        string
+		retries   int
+		want      string
+		wantErr   error
+		wantCalls int
+	}{
+		{name: "recovers", vm: "vm", retries: 2, want: "192.168.64.5", wantCalls: 3},
+		{name: "more retries than needed", vm: "vm", retries: 5, want: "192.168.64.5", wantCalls: 3},
+		{name: "out of retries", vm: "vm", retries: 1, wantErr: ErrIPNotReady, wantCalls: 2},
+		{name: "no retries", vm: "vm", wantErr: ErrIPNotReady, wantCalls: 1},
+		{name: "hard error", vm: "missing", retries: 2, wantErr: ErrVMNotFound, wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeTart(t, script)
+			clk := newFakeClock()
+			f.clk = clk
+			got, err := f.IPWithOptions(tt.vm, IPOptions{Retries: tt.retries, RetryDelay: 2 * time.Second})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("IPWithOptions = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("IPWithOptions = %q, want %q", got, tt.want)
+			}
+			if ips := f.commandCalls(t, "ip"); len(ips) != tt.wantCalls {
+				t.Errorf("got %d IP lookups, want %d", len(ips), tt.wantCalls)
+			}
+			for _, d := range clk.recordedWaits() {
+				if d != 2*time.Second {
+					t.Errorf("waited %v between lookups, want the RetryDelay", d)
+				}
+			}
+			if waits := clk.recordedWaits(); len(waits) != tt.wantCalls-1 {
+				t.Errorf("waited %d times, want %d", len(waits), tt.wantCalls-1)
+			}
+		})
+	}
+}
+
+func TestListAll(t *testing.T) {
+	tests := []struct {
+		name    string
+		remote  string
+		want    []VMState
+		wantErr bool
+	}{
+		{
+			name:   "both sources",
+			remote: `echo '[{"name":"ghcr.io/org/img:latest","state":"stopped"}]'; exit 0`,
+			want: []VMState{
+				{Name: "vm", State: "stopped", Source: SourceLocal},
+				{Name: "ghcr.io/org/img:latest", State: "stopped", Source: SourceRemote},
+			},
+		},
+		{
+			name:    "remote fails",
+			remote:  `echo "not logged in" >&2; exit 1`,
+			want:    []VMState{{Name: "vm", State: "stopped", Source: SourceLocal}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeTart(t, `
+if [ "$1" = list ]; then
+	case "$5" in
+	local) echo '[{"name":"vm","state":"stopped"}]'; exit 0;;
+	remote) `+tt.remote+`;;
+	esac
+fi`)
+			got, err := f.ListAll()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ListAll error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListAll = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListNames(t *testing.T) {
+	f := newFakeTart(t, `
+if [ "$1" = list ]; then
+	case "$5" in
+	local) echo '[{"name":"a","state":"stopped"},{"name":"b","state":"running"}]'; exit 0;;
+	remote) echo '[{"name":"ghcr.io/org/img:latest","state":"stopped"}]'; exit 0;;
+	esac
+fi`)
+	local, remote := SourceLocal, SourceRemote
+	tests := []struct {
+		name    string
+		source  *string
+		want    []string
+		wantErr bool
+	}{
+		{name: "local", source: &local, want: []string{"a", "b"}},
+		{name: "remote", source: &remote, want: []string{"ghcr.io/org/img:latest"}},
+		{name: "invalid source", source: new(string), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := f.ListNames(ListOptions{Source: tt.source})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ListNames error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListNames = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStates(t *testing.T) {
+	f := newFakeTart(t, "")
+	f.setList(t, `[{"name":"a","state":"running"},{"name":"b","state":"stopped"},{"name":"c","state":"stopped"}]`)
+	got, err := f.States([]string{"a", "b", "missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]VMState{
+		"a": {Name: "a", State: "running"},
+		"b": {Name: "b", State: "stopped"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("States = %+v, want %+v", got, want)
+	}
+	if lists := f.commandCalls(t, "list"); len(lists) != 1 {
+		t.Errorf("got %d listings, want 1", len(lists))
+	}
+}
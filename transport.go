@@ -0,0 +1,140 @@
+package tart
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Transport builds the processes that run tart commands, letting a Tart
+// instance control Tart on another machine.
+type Transport interface {
+	// Command returns a command that runs tart with the given arguments and
+	// additional environment variables, killed if the context is done. The
+	// caller sets up its stdout and stderr and starts it; Command may set
+	// its stdin, which the caller must then leave alone.
+	Command(ctx context.Context, env []string, args ...string) *exec.Cmd
+}
+
+// LocalTransport runs tart on this machine. It's used when a Tart instance's
+// Transport is nil.
+type LocalTransport struct{}
+
+// Command returns a command that runs the tart found in PATH.
+func (LocalTransport) Command(ctx context.Context, env []string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "tart", args...)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	return cmd
+}
+
+// SSHTransport runs tart on a remote Mac over SSH, for example to control a
+// Mac mini farm from a Linux CI controller. It uses the system ssh client in
+// batch mode, so authentication relies on the caller's SSH keys, agent and
+// known_hosts. The output of each command is streamed back as it's produced.
+// Environment variables, which include registry Credentials, are sent over
+// the connection's stdin rather than on a command line, so they don't show
+// up in process listings or shell history on either host; their values
+// can't contain newlines.
+//
+// Only the tart commands themselves run remotely. Methods that read or write
+// a VM's bundle directly, such as Config, SetConfigAtomic, SuspendFile and
+// SetConfig with a specific MACAddress, return an error wrapping
+// ErrUnsupported with this transport. Metadata and IPSW files, and host
+// checks, still act on this machine.
+// Cancelling a command closes the SSH connection, which doesn't always stop
+// the remote tart process; RunHandle.Stop and Kill stop a remote VM with
+// tart stop.
+type SSHTransport struct {
+	// Host is the remote host, e.g. "mini-01.local".
+	Host string `json:"host"`
+	// User is the remote user. Empty uses the ssh client's default.
+	User string `json:"user"`
+	// Port is the remote SSH port. Zero uses the ssh client's default.
+	Port int `json:"port"`
+	// IdentityFile is the private key to authenticate with. Empty uses the
+	// ssh client's default keys and agent.
+	IdentityFile string `json:"identityFile"`
+	// Options are extra ssh -o options, e.g. "ConnectTimeout=10".
+	Options []string `json:"options"`
+	// TartPath is the path of tart on the remote host. Empty means "tart",
+	// which must then be in the remote PATH for non-interactive sessions.
+	TartPath string `json:"tartPath"`
+}
+
+// Command returns an ssh command that runs tart on the remote host with the
+// environment variables set.
+func (s SSHTransport) Command(ctx context.Context, env []string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "ssh", s.sshArgs(s.remoteCommand(len(env), args))...)
+	if len(env) > 0 {
+		cmd.Stdin = strings.NewReader(strings.Join(env, "\n") + "\n")
+	}
+	return cmd
+}
+
+// sshArgs returns the arguments to ssh for running a remote command.
+func (s SSHTransport) sshArgs(command string) []string {
+	args := []string{"-o", "BatchMode=yes"}
+	for _, opt := range s.Options {
+		args = append(args, "-o", opt)
+	}
+	if s.Port != 0 {
+		args = append(args, "-p", strconv.Itoa(s.Port))
+	}
+	if s.IdentityFile != "" {
+		args = append(args, "-i", s.IdentityFile)
+	}
+	host := s.Host
+	if s.User != "" {
+		host = s.User + "@" + host
+	}
+	return append(args, "--", host, command)
+}
+
+// remoteCommand renders the shell command the remote host runs. It first
+// reads and exports the given number of environment variables, one per line
+// of stdin, then runs tart. The remote shell parses it, so every word is
+// quoted.
+func (s SSHTransport) remoteCommand(envCount int, args []string) string {
+	tart := s.TartPath
+	if tart == "" {
+		tart = "tart"
+	}
+	var words []string
+	for i := 0; i < envCount; i++ {
+		words = append(words, `IFS= read -r kv && export "$kv" &&`)
+	}
+	words = append(words, "exec", shellQuote(tart))
+	for _, arg := range args {
+		words = append(words, shellQuote(arg))
+	}
+	return strings.Join(words, " ")
+}
+
+// shellQuote quotes s as a single word for a POSIX shell.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_=+./:@,%") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// remote reports whether tart commands run through a remote Transport, so
+// the VM bundles they manage aren't on this machine.
+func (t *Tart) remote() bool {
+	_, local := t.transport().(LocalTransport)
+	return !local
+}
+
+// requireLocal returns an error wrapping ErrUnsupported if tart runs through
+// a remote Transport, for methods that access a VM's bundle directly.
+func (t *Tart) requireLocal(what string) error {
+	if t.remote() {
+		return fmt.Errorf("%w: %s needs the VM's bundle on this machine, but tart runs through a remote transport", ErrUnsupported, what)
+	}
+	return nil
+}
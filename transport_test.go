@@ -0,0 +1,138 @@
+package tart
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "list", want: "list"},
+		{in: "", want: "''"},
+		{in: "with space", want: "'with space'"},
+		{in: "it's", want: `'it'\''s'`},
+		{in: "$HOME;rm", want: "'$HOME;rm'"},
+		{in: "ghcr.io/org/image:1.0", want: "ghcr.io/org/image:1.0"},
+	}
+	for _, tt := range tests {
+		if got := shellQuote(tt.in); got != tt.want {
+			t.Errorf("shellQuote(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSSHTransportKeepsEnvOffCommandLine(t *testing.T) {
+	s := SSHTransport{Host: "mini", User: "admin", Port: 2222}
+	env := (&Credentials{Username: "bot", Password: "hunter2"}).env()
+	cmd := s.Command(context.Background(), env, "pull", "ghcr.io/org/image")
+	for _, arg := range cmd.Args {
+		if strings.Contains(arg, "hunter2") || strings.Contains(arg, "bot") {
+			t.Errorf("argument %q exposes credentials", arg)
+		}
+	}
+	if cmd.Stdin == nil {
+		t.Fatal("credentials not sent over stdin")
+	}
+	stdin, _ := io.ReadAll(cmd.Stdin)
+	if !strings.Contains(string(stdin), "TART_REGISTRY_PASSWORD=hunter2\n") {
+		t.Errorf("stdin = %q", stdin)
+	}
+	if i := indexOf(cmd.Args, "admin@mini"); i < 0 || cmd.Args[i-1] != "--" {
+		t.Errorf("args = %q, want the host after --", cmd.Args)
+	}
+}
+
+func TestSSHTransportRemoteCommand(t *testing.T) {
+	dir := t.TempDir()
+	tart := filepath.Join(dir, "my tart")
+	script := "#!/bin/sh\necho \"$TART_HOME|$TART_REGISTRY_PASSWORD\"\nfor a in \"$@\"; do echo \"[$a]\"; done\n"
+	if err := os.WriteFile(tart, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	s := SSHTransport{TartPath: tart}
+	env := []string{"TART_HOME=/remote/tart home", "TART_REGISTRY_PASSWORD=p'w $x"}
+	cmd := exec.Command("sh", "-c", s.remoteCommand(len(env), []string{"run", "vm name", "--dir", "a:'b'"}))
+	cmd.Stdin = strings.NewReader(strings.Join(env, "\n") + "\n")
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "/remote/tart home|p'w $x\n[run]\n[vm name]\n[--dir]\n[a:'b']\n"
+	if string(output) != want {
+		t.Errorf("output = %q, want %q", output, want)
+	}
+}
+
+// indexOf returns the index of s in list, or -1.
+func indexOf(list []string, s string) int {
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestRemoteTransportLocalBundleAccess(t *testing.T) {
+	tests := []struct {
+		name    string
+		call    func(f *fakeTart) error
+		wantErr error
+	}{
+		{name: "export", call: func(f *fakeTart) error { return f.Export("vm", "/remote/vm.tvm") }},
+		{name: "set config", call: func(f *fakeTart) error { return f.SetConfig("vm", VMConfig{CPUCount: 2}) }},
+		{
+			name:    "set MAC address",
+			call:    func(f *fakeTart) error { return f.SetConfig("vm", VMConfig{MACAddress: "7e:00:00:00:00:01"}) },
+			wantErr: ErrUnsupported,
+		},
+		{
+			name:    "config",
+			call:    func(f *fakeTart) error { _, err := f.Config("vm"); return err },
+			wantErr: ErrUnsupported,
+		},
+		{
+			name:    "set config atomically",
+			call:    func(f *fakeTart) error { return f.SetConfigAtomic("vm", VMConfig{CPUCount: 2}) },
+			wantErr: ErrUnsupported,
+		},
+		{
+			name:    "export to stream",
+			call:    func(f *fakeTart) error { return f.ExportTo("vm", io.Discard) },
+			wantErr: ErrUnsupported,
+		},
+		{
+			name:    "run with MAC seed",
+			call:    func(f *fakeTart) error { return f.Run("vm", RunOptions{MACSeed: "seed"}) },
+			wantErr: ErrUnsupported,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeTart(t, runningScript)
+			f.Transport = remoteTransport{}
+			f.setList(t, `[{"name":"vm","state":"stopped"}]`)
+			err := tt.call(f)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("error = %v, want nil", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			for _, call := range f.commandCalls(t, "run") {
+				if call[1] != "--help" {
+					t.Errorf("VM was started: %q", call)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package tart
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// DiskUsageOf returns the number of bytes used by a VM's bundle, computed by
+// walking the bundle directory and summing the sizes of its regular files.
+// This is the logical size: sparse files such as disk images count in full,
+// so the space actually allocated on disk may be considerably smaller.
+// Symlinks inside the bundle are not followed.
+// It returns an error if the bundle can't be found or walked.
+func (t *Tart) DiskUsageOf(name string) (int64, error) {
+	path, err := t.Path(name)
+	if err != nil {
+		return 0, err
+	}
+	return dirSize(path)
+}
+
+// dirSize sums the sizes of the regular files below root.
+func dirSize(root string) (int64, error) {
+	// The root itself may be a symlink, e.g. an OCI tag pointing at a digest
+	root, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve %s: %w", root, err)
+	}
+	var size int64
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to compute disk usage of %s: %w", root, err)
+	}
+	return size, nil
+}
+
+// StoreUsage represents the disk space used by Tart's config directory.
+// All sizes are logical byte counts; see DiskUsageOf.
+type StoreUsage struct {
+	// Total covers everything in the config directory.
+	Total int64 `json:"total"`
+	// VMs maps each local VM to the size of its bundle.
+	VMs map[string]int64 `json:"vms"`
+	// OCICache is the size of the cache of pulled OCI images.
+	OCICache int64 `json:"ociCache"`
+	// IPSWCache is the size of the cache of downloaded IPSWs.
+	IPSWCache int64 `json:"ipswCache"`
+}
+
+// StoreUsage computes the disk space used by Tart's config directory, with a
+// breakdown per local VM and per cache. Directories are walked concurrently.
+// It returns an error if any directory can't be walked.
+func (t *Tart) StoreUsage() (StoreUsage, error) {
+	usage := StoreUsage{VMs: map[string]int64{}}
+	root := t.EffectiveConfigDir()
+
+	entries, err := os.ReadDir(filepath.Join(root, "vms"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return usage, fmt.Errorf("failed to list VM bundles: %w", err)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var errs []error
+	measure := func(path string, record func(int64)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			size, err := dirSize(path)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if !errors.Is(err, os.ErrNotExist) {
+					errs = append(errs, err)
+				}
+				return
+			}
+			record(size)
+		}()
+	}
+
+	measure(root, func(size int64) { usage.Total = size })
+	measure(filepath.Join(root, "cache", "OCIs"), func(size int64) { usage.OCICache = size })
+	measure(filepath.Join(root, "cache", "IPSWs"), func(size int64) { usage.IPSWCache = size })
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		measure(filepath.Join(root, "vms", name), func(size int64) { usage.VMs[name] = size })
+	}
+	wg.Wait()
+	return usage, errors.Join(errs...)
+}
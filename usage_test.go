@@ -0,0 +1,73 @@
+package tart
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeSized writes a file of the given size below the config directory.
+func writeSized(t *testing.T, f *fakeTart, path string, size int) {
+	t.Helper()
+	path = filepath.Join(f.ConfigDir, path)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiskUsageOf(t *testing.T) {
+	f := newFakeTart(t, "")
+	writeSized(t, f, "vms/vm/config.json", 100)
+	writeSized(t, f, "vms/vm/nvram.bin", 20)
+	writeSized(t, f, "vms/vm/logs/boot.log", 3)
+	writeSized(t, f, "outside", 1000)
+	if err := os.Symlink(filepath.Join(f.ConfigDir, "outside"), filepath.Join(f.vmDir("vm"), "link")); err != nil {
+		t.Fatal(err)
+	}
+	got, err := f.DiskUsageOf("vm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 123 {
+		t.Errorf("DiskUsageOf = %d, want 123", got)
+	}
+	if _, err := f.DiskUsageOf("missing"); err == nil {
+		t.Error("DiskUsageOf missing VM succeeded")
+	}
+}
+
+func TestDiskUsageOfSymlinkedBundle(t *testing.T) {
+	f := newFakeTart(t, "")
+	writeSized(t, f, "cache/OCIs/ghcr.io/org/img/sha256:abc/disk.img", 50)
+	link := filepath.Join(f.ConfigDir, "cache", "OCIs", "ghcr.io", "org", "img", "latest")
+	if err := os.Symlink("sha256:abc", link); err != nil {
+		t.Fatal(err)
+	}
+	got, err := f.DiskUsageOf("ghcr.io/org/img:latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 50 {
+		t.Errorf("DiskUsageOf = %d, want 50", got)
+	}
+}
+
+func TestStoreUsage(t *testing.T) {
+	f := newFakeTart(t, "")
+	writeSized(t, f, "vms/a/disk.img", 10)
+	writeSized(t, f, "vms/b/disk.img", 20)
+	writeSized(t, f, "cache/OCIs/ghcr.io/org/img/latest/disk.img", 30)
+	writeSized(t, f, "cache/IPSWs/restore.ipsw", 40)
+	got, err := f.StoreUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := StoreUsage{Total: 100, VMs: map[string]int64{"a": 10, "b": 20}, OCICache: 30, IPSWCache: 40}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("StoreUsage = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,163 @@
+package tart
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MinimumVersion is the oldest Tart release this package is tested against.
+const MinimumVersion = "2.0.0"
+
+// Version retrieves the version of the installed Tart.
+// It returns an error if the version can't be retrieved.
+func (t *Tart) Version() (string, error) {
+	output, err := t.run("--version")
+	if err != nil {
+		return "", fmt.Errorf("failed to get Tart version: %w, output: %s", err, string(output))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// subcommands returns the subcommands listed by `tart --help`.
+func (t *Tart) subcommands() (map[string]bool, error) {
+	output, err := t.run("--help")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Tart help: %w, output: %s", err, string(output))
+	}
+	commands := map[string]bool{}
+	inSection := false
+	for _, line := range strings.Split(string(output), "\n") {
+		if strings.HasPrefix(line, "SUBCOMMANDS:") {
+			inSection = true
+			continue
+		}
+		if !inSection {
+			continue
+		}
+		if !strings.HasPrefix(line, "  ") {
+			if strings.TrimSpace(line) != "" {
+				break
+			}
+			continue
+		}
+		if fields := strings.Fields(line); len(fields) > 0 && fields[0] != "See" {
+			commands[fields[0]] = true
+		}
+	}
+	return commands, nil
+}
+
+// flagPattern matches the long flags in a subcommand's help.
+var flagPattern = regexp.MustCompile(`--[a-z0-9][a-z0-9-]*`)
+
+// SupportedRunFlags returns the long flags, such as "--nested", accepted by
+// the installed Tart's run command, parsed from `tart run --help`. The result
+// is cached for the lifetime of the instance.
+// It returns an error if the help can't be retrieved.
+func (t *Tart) SupportedRunFlags() (map[string]bool, error) {
+	return t.supportedFlags("run")
+}
+
+// supportedFlags returns the long flags a subcommand accepts, parsed from its
+// help and cached for the lifetime of the instance.
+func (t *Tart) supportedFlags(command string) (map[string]bool, error) {
+	t.mu.Lock()
+	cached := t.flags[command]
+	t.mu.Unlock()
+	if cached != nil {
+		return copyFlags(cached), nil
+	}
+	output, err := t.run(command, "--help")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Tart %s help: %w, output: %s", command, err, string(output))
+	}
+	flags := map[string]bool{}
+	for _, flag := range flagPattern.FindAllString(string(output), -1) {
+		flags[flag] = true
+	}
+	t.mu.Lock()
+	if t.flags == nil {
+		t.flags = map[string]map[string]bool{}
+	}
+	t.flags[command] = flags
+	t.mu.Unlock()
+	return copyFlags(flags), nil
+}
+
+// copyFlags returns a copy of a flag set so callers can't modify the cache.
+func copyFlags(flags map[string]bool) map[string]bool {
+	ret := make(map[string]bool, len(flags))
+	for flag := range flags {
+		ret[flag] = true
+	}
+	return ret
+}
+
+// checkRunFlags returns an error wrapping ErrUnsupported if the run arguments
+// use a flag the installed Tart doesn't accept. If the supported flags can't
+// be determined the check is skipped and Tart reports any problem itself.
+func (t *Tart) checkRunFlags(args []string) error {
+	flags, err := t.SupportedRunFlags()
+	if err != nil {
+		return nil
+	}
+	var missing []string
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "--") && !flags[arg] {
+			missing = append(missing, arg)
+		}
+	}
+	if len(missing) > 0 {
+		version, _ := t.Version()
+		return fmt.Errorf("%w: tart %s run doesn't accept %s", ErrUnsupported, version, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// requireCommand returns an error wrapping ErrUnsupported if the installed
+// Tart lacks a subcommand.
+func (t *Tart) requireCommand(command string) error {
+	commands, err := t.subcommands()
+	if err != nil {
+		return err
+	}
+	if !commands[command] {
+		version, _ := t.Version()
+		return fmt.Errorf("%w: tart %s has no %s command", ErrUnsupported, version, command)
+	}
+	return nil
+}
+
+// compareVersions compares two dotted version strings numerically, returning
+// -1, 0 or 1. Missing components count as zero, and anything after the
+// numeric part of a component (e.g. "-beta") is ignored.
+func compareVersions(a string, b string) int {
+	as := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		x, y := versionComponent(as, i), versionComponent(bs, i)
+		if x < y {
+			return -1
+		}
+		if x > y {
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionComponent returns the numeric value of the i-th version component.
+func versionComponent(parts []string, i int) int {
+	if i >= len(parts) {
+		return 0
+	}
+	p := parts[i]
+	end := 0
+	for end < len(p) && p[end] >= '0' && p[end] <= '9' {
+		end++
+	}
+	n, _ := strconv.Atoi(p[:end])
+	return n
+}
@@ -0,0 +1,42 @@
+package tart
+
+import "testing"
+
+func TestSupportedRunFlags(t *testing.T) {
+	f := newFakeTart(t, `
+case "$2" in
+--help) printf '  --no-graphics           Run without graphics\n  --dir <dir>             Share a directory\n  --net-bridged <iface>   Use bridged networking\n'; exit 0;;
+esac`)
+	flags, err := f.SupportedRunFlags()
+	if err != nil {
+		t.Fatalf("SupportedRunFlags: %v", err)
+	}
+	for _, flag := range []string{"--no-graphics", "--dir", "--net-bridged"} {
+		if !flags[flag] {
+			t.Errorf("flags = %v, missing %s", flags, flag)
+		}
+	}
+	if flags["--suspendable"] {
+		t.Errorf("flags = %v, want no --suspendable", flags)
+	}
+
+	// The result is cached, and callers can't modify the cache
+	flags["--suspendable"] = true
+	again, err := f.SupportedRunFlags()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again["--suspendable"] {
+		t.Error("modifying the result changed the cache")
+	}
+	if runs := f.commandCalls(t, "run"); len(runs) != 1 {
+		t.Errorf("run calls = %q, want a single help probe", runs)
+	}
+}
+
+func TestSupportedRunFlagsError(t *testing.T) {
+	f := newFakeTart(t, `[ "$1" = run ] && { echo "boom" >&2; exit 1; }`)
+	if _, err := f.SupportedRunFlags(); err == nil {
+		t.Error("SupportedRunFlags succeeded, want the help failure")
+	}
+}
@@ -0,0 +1,62 @@
+package tart
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// vmIDPath returns the path of the sidecar holding a VM's stable ID.
+func (t *Tart) vmIDPath(name string) string {
+	return filepath.Join(t.EffectiveConfigDir(), name+".id")
+}
+
+// VMID returns a stable identifier for a local VM, for tracking it in a
+// database through renames. Tart doesn't assign VMs an ID, so the first call
+// generates a random UUID and records it in a sidecar file named <name>.id in
+// the config directory, next to the metadata sidecar.
+//
+// The ID survives Rename, SetConfig and restarts, and is removed by Delete.
+// Clones and imported VMs get an ID of their own. It doesn't survive renaming
+// or deleting the VM with the tart CLI directly, or removing the sidecar.
+// It returns an error wrapping ErrVMNotFound if the VM doesn't exist, or an
+// error if the ID can't be read or recorded.
+func (t *Tart) VMID(name string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	data, err := os.ReadFile(t.vmIDPath(name))
+	if err == nil {
+		return strings.TrimSpace(string(data)), nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("failed to read VM ID: %w", err)
+	}
+	if _, err := os.Stat(t.vmDir(name)); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("%w: %s", ErrVMNotFound, name)
+		}
+		return "", fmt.Errorf("failed to inspect VM: %w", err)
+	}
+	id, err := newUUID()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate VM ID: %w", err)
+	}
+	if err := t.writeFile(t.vmIDPath(name), []byte(id+"\n")); err != nil {
+		return "", fmt.Errorf("failed to write VM ID: %w", err)
+	}
+	return id, nil
+}
+
+// newUUID returns a random version 4 UUID.
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
@@ -0,0 +1,57 @@
+package tart
+
+import (
+	"errors"
+	"os"
+	"regexp"
+	"testing"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestVMID(t *testing.T) {
+	f := newFakeTart(t, `
+case "$1" in
+rename)
+	mv "$TART_HOME/vms/$2" "$TART_HOME/vms/$3"
+	echo "[{\"name\":\"$3\",\"state\":\"stopped\"}]" > "$FAKE_TART_DIR/list.json"
+	exit 0;;
+delete)
+	rm -r "$TART_HOME/vms/$2"
+	exit 0;;
+esac`)
+	f.addVM(t, "old", `{}`)
+	f.addVM(t, "other", `{}`)
+	f.setList(t, `[{"name":"old","state":"stopped"},{"name":"other","state":"stopped"}]`)
+
+	id, err := f.VMID("old")
+	if err != nil {
+		t.Fatalf("VMID: %v", err)
+	}
+	if !uuidPattern.MatchString(id) {
+		t.Errorf("VMID = %q, want a version 4 UUID", id)
+	}
+	if again, err := f.VMID("old"); err != nil || again != id {
+		t.Errorf("second VMID = %q, %v, want %q", again, err, id)
+	}
+	if other, err := f.VMID("other"); err != nil || other == id {
+		t.Errorf("another VM's ID = %q, %v, want a distinct ID", other, err)
+	}
+
+	if _, err := f.Rename("old", "new"); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	if renamed, err := f.VMID("new"); err != nil || renamed != id {
+		t.Errorf("VMID after rename = %q, %v, want %q", renamed, err, id)
+	}
+
+	if err := f.Delete("new"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := os.Stat(f.vmIDPath("new")); !os.IsNotExist(err) {
+		t.Errorf("ID sidecar survived Delete: %v", err)
+	}
+	if _, err := f.VMID("new"); !errors.Is(err, ErrVMNotFound) {
+		t.Errorf("VMID after delete = %v, want ErrVMNotFound", err)
+	}
+}
@@ -0,0 +1,84 @@
+package tart
+
+import (
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// VNCConnection represents the details needed to connect to a VM's VNC server.
+type VNCConnection struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Password string `json:"-"`
+}
+
+// String returns the connection as a vnc:// URL with the password redacted.
+func (c VNCConnection) String() string {
+	u := url.URL{Scheme: "vnc", Host: net.JoinHostPort(c.Host, strconv.Itoa(c.Port))}
+	if c.Password != "" {
+		u.User = url.UserPassword("", "REDACTED")
+	}
+	return u.String()
+}
+
+// URL returns the connection as a vnc:// URL including the password.
+func (c VNCConnection) URL() string {
+	u := url.URL{Scheme: "vnc", Host: net.JoinHostPort(c.Host, strconv.Itoa(c.Port))}
+	if c.Password != "" {
+		u.User = url.UserPassword("", c.Password)
+	}
+	return u.String()
+}
+
+var (
+	// vncURLPattern matches the URL Tart prints when VNC is enabled, such as
+	// "vnc://:ruby-wolf@127.0.0.1:59354".
+	vncURLPattern = regexp.MustCompile(`vnc://[^\s]+`)
+	// vncPasswordPattern matches a password printed on its own line.
+	vncPasswordPattern = regexp.MustCompile(`(?i)\bpassword:\s*(\S+)`)
+)
+
+// parseVNCLine updates conn from a line of Tart's output, returning whether
+// the line carried VNC details.
+func parseVNCLine(line string, conn *VNCConnection) bool {
+	if raw := vncURLPattern.FindString(line); raw != "" {
+		u, err := url.Parse(strings.TrimRight(raw, ".,;"))
+		if err != nil {
+			return false
+		}
+		conn.Host = u.Hostname()
+		conn.Port, _ = strconv.Atoi(u.Port())
+		if conn.Port == 0 {
+			conn.Port = 5900
+		}
+		if password, ok := u.User.Password(); ok {
+			conn.Password = password
+		}
+		return true
+	}
+	if m := vncPasswordPattern.FindStringSubmatch(line); m != nil {
+		conn.Password = m[1]
+		return true
+	}
+	return false
+}
+
+// redactVNC replaces VNC passwords in a line of output.
+func redactVNC(line string) string {
+	line = vncURLPattern.ReplaceAllStringFunc(line, func(raw string) string {
+		if u, err := url.Parse(strings.TrimRight(raw, ".,;")); err == nil && u.User != nil {
+			if _, ok := u.User.Password(); ok {
+				u.User = url.UserPassword(u.User.Username(), "REDACTED")
+				return u.String() + raw[len(strings.TrimRight(raw, ".,;")):]
+			}
+		}
+		return raw
+	})
+	return vncPasswordPattern.ReplaceAllStringFunc(line, func(m string) string {
+		i := strings.Index(strings.ToLower(m), "password:")
+		return m[:i] + "password: REDACTED"
+	})
+}
@@ -0,0 +1,84 @@
+package tart
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseVNCLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want VNCConnection
+		ok   bool
+	}{
+		{
+			line: "Opening vnc://:ruby-wolf@127.0.0.1:59354...",
+			want: VNCConnection{Host: "127.0.0.1", Port: 59354, Password: "ruby-wolf"},
+			ok:   true,
+		},
+		{line: "vnc://192.168.64.2", want: VNCConnection{Host: "192.168.64.2", Port: 5900}, ok: true},
+		{line: "VNC password: hunter2", want: VNCConnection{Password: "hunter2"}, ok: true},
+		{line: "booting", ok: false},
+	}
+	for _, tt := range tests {
+		var conn VNCConnection
+		ok := parseVNCLine(tt.line, &conn)
+		if ok != tt.ok || conn != tt.want {
+			t.Errorf("parseVNCLine(%q) = %+v, %v; want %+v, %v", tt.line, conn, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRedactVNC(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{line: "Opening vnc://:ruby-wolf@127.0.0.1:59354...", want: "Opening vnc://:REDACTED@127.0.0.1:59354..."},
+		{line: "VNC Password: hunter2", want: "VNC password: REDACTED"},
+		{line: "vnc://127.0.0.1:5900", want: "vnc://127.0.0.1:5900"},
+	}
+	for _, tt := range tests {
+		if got := redactVNC(tt.line); got != tt.want {
+			t.Errorf("redactVNC(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+	conn := VNCConnection{Host: "127.0.0.1", Port: 5900, Password: "hunter2"}
+	if s := conn.String(); strings.Contains(s, "hunter2") {
+		t.Errorf("String = %q, leaks the password", s)
+	}
+	if u := conn.URL(); !strings.Contains(u, "hunter2") {
+		t.Errorf("URL = %q, want the password", u)
+	}
+}
+
+func TestRunHandleVNC(t *testing.T) {
+	f := newFakeTart(t, `
+case "$2" in
+--help) echo "--vnc"; exit 0;;
+esac
+if [ "$1" = run ]; then
+	echo "vnc://:ruby-wolf@127.0.0.1:59354"
+fi`+runningScript)
+	f.setList(t, `[{"name":"vm","state":"stopped"}]`)
+	var serial syncBuffer
+	h, err := f.RunDetached("vm", RunOptions{VNC: true, SerialOutput: &serial})
+	if err != nil {
+		t.Fatalf("RunDetached: %v", err)
+	}
+	defer h.Stop()
+	want := VNCConnection{Host: "127.0.0.1", Port: 59354, Password: "ruby-wolf"}
+	if conn, ok := h.VNC(); !ok || conn != want {
+		t.Errorf("VNC = %+v, %v; want %+v", conn, ok, want)
+	}
+	h.Stop()
+	select {
+	case <-h.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("VM still running")
+	}
+	if out := serial.String(); strings.Contains(out, "ruby-wolf") || !strings.Contains(out, "REDACTED") {
+		t.Errorf("serial output = %q, want the password redacted", out)
+	}
+}
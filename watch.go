@@ -0,0 +1,110 @@
+package tart
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// Constants representing the kinds of VM events reported by Watch.
+const (
+	// EventCreated is reported when a local VM appears.
+	EventCreated = "created"
+	// EventDeleted is reported when a local VM disappears, including by
+	// being renamed.
+	EventDeleted = "deleted"
+	// EventStateChanged is reported when a VM's state changes, for example
+	// from stopped to running.
+	EventStateChanged = "stateChanged"
+)
+
+// Event describes a change to a local VM observed by Watch.
+type Event struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	// State is the VM's new state, empty for EventDeleted, and
+	// PreviousState its state before the change, empty for EventCreated.
+	State         string    `json:"state"`
+	PreviousState string    `json:"previousState"`
+	Time          time.Time `json:"time"`
+}
+
+// Watch reports changes to local VMs, including ones made outside this
+// package, until the context is done, when the channel is closed. Tart has
+// no events command, so changes are found by listing VMs repeatedly, spaced
+// out with the instance's PollBackoff, which is reset whenever something
+// changes; events are therefore delayed by up to one poll and changes that
+// are undone between polls go unnoticed. Failed listings are retried.
+// It returns an error if the initial listing fails.
+func (t *Tart) Watch(ctx context.Context) (<-chan Event, error) {
+	current, err := t.watchSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		backoff := t.pollBackoff()
+		for {
+			if err := backoff.Wait(ctx); err != nil {
+				return
+			}
+			next, err := t.watchSnapshot()
+			if err != nil {
+				continue
+			}
+			changes := diffSnapshots(current, next, t.clock().Now())
+			current = next
+			if len(changes) > 0 {
+				backoff.Reset()
+			}
+			for _, e := range changes {
+				select {
+				case events <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+// watchSnapshot returns the state of each local VM.
+func (t *Tart) watchSnapshot() (map[string]string, error) {
+	vms, err := t.List(ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	states := make(map[string]string, len(vms))
+	for _, vm := range vms {
+		if vm.Source == "" || vm.Source == SourceLocal {
+			states[vm.Name] = vm.State
+		}
+	}
+	return states, nil
+}
+
+// diffSnapshots returns the events that turn one snapshot into the next,
+// sorted by VM name.
+func diffSnapshots(prev map[string]string, next map[string]string, now time.Time) []Event {
+	var events []Event
+	for name, state := range next {
+		old, ok := prev[name]
+		switch {
+		case !ok:
+			events = append(events, Event{Type: EventCreated, Name: name, State: state, Time: now})
+		case old != state:
+			events = append(events, Event{Type: EventStateChanged, Name: name, State: state, PreviousState: old, Time: now})
+		}
+	}
+	for name, state := range prev {
+		if _, ok := next[name]; !ok {
+			events = append(events, Event{Type: EventDeleted, Name: name, PreviousState: state, Time: now})
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Name < events[j].Name
+	})
+	return events
+}
@@ -0,0 +1,92 @@
+package tart
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		prev map[string]string
+		next map[string]string
+		want []Event
+	}{
+		{name: "unchanged", prev: map[string]string{"a": "stopped"}, next: map[string]string{"a": "stopped"}},
+		{
+			name: "created",
+			prev: map[string]string{},
+			next: map[string]string{"a": "stopped"},
+			want: []Event{{Type: EventCreated, Name: "a", State: "stopped", Time: now}},
+		},
+		{
+			name: "deleted",
+			prev: map[string]string{"a": "stopped"},
+			next: map[string]string{},
+			want: []Event{{Type: EventDeleted, Name: "a", PreviousState: "stopped", Time: now}},
+		},
+		{
+			name: "renamed",
+			prev: map[string]string{"a": "stopped"},
+			next: map[string]string{"b": "stopped"},
+			want: []Event{
+				{Type: EventDeleted, Name: "a", PreviousState: "stopped", Time: now},
+				{Type: EventCreated, Name: "b", State: "stopped", Time: now},
+			},
+		},
+		{
+			name: "state changed",
+			prev: map[string]string{"a": "stopped", "b": "running"},
+			next: map[string]string{"a": "running", "b": "running"},
+			want: []Event{{Type: EventStateChanged, Name: "a", State: "running", PreviousState: "stopped", Time: now}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffSnapshots(tt.prev, tt.next, now); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffSnapshots = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatch(t *testing.T) {
+	f := newFakeTart(t, "")
+	f.setList(t, `[{"name":"a","state":"stopped","source":"local"},{"name":"ghcr.io/org/img:latest","state":"stopped","source":"remote"}]`)
+	f.PollBackoff = Backoff{Initial: 10 * time.Millisecond, Jitter: -1}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	events, err := f.Watch(ctx)
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	f.setList(t, `[{"name":"a","state":"running","source":"local"},{"name":"b","state":"stopped","source":"local"}]`)
+
+	var got []string
+	for len(got) < 2 {
+		select {
+		case e := <-events:
+			got = append(got, e.Type+" "+e.Name)
+		case <-ctx.Done():
+			t.Fatalf("got events %q before the deadline, want two", got)
+		}
+	}
+	want := []string{EventStateChanged + " a", EventCreated + " b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestWatchInitialListFails(t *testing.T) {
+	f := newFakeTart(t, `[ "$1" = list ] && { echo "boom" >&2; exit 1; }`)
+	if _, err := f.Watch(context.Background()); err == nil {
+		t.Error("Watch succeeded, want the listing failure")
+	}
+}